@@ -1,56 +1,61 @@
 package formatter
 
 import (
-	"encoding/json"
 	"fmt"
-	"github.com/Masterminds/sprig"
-	"github.com/willf/pad"
-	"gopkg.in/yaml.v2"
 	"io"
-	"os"
-	"text/template"
+	"strings"
 )
 
 type ChangelogFormatter struct {
 	release  *ReleaseDTO
 	colorize bool
-	output   io.Writer
+	templateOptions
+	// name is the name of a published release, the changelog is the one of the next release when empty
+	name string
 }
 
 func NewChangelogFormatter(release *ReleaseDTO, colorize bool) *ChangelogFormatter {
 	return &ChangelogFormatter{
 		release:  release,
 		colorize: colorize,
-		output:   os.Stdout,
 	}
 }
 
-func (c *ChangelogFormatter) Json() {
-	encoder := json.NewEncoder(c.output)
-	encoder.SetIndent("", "  ")
-	_ = encoder.Encode(c.release)
+// SetRelease tells the changelog is the one of a published release
+func (c *ChangelogFormatter) SetRelease(name string) {
+	c.name = name
 }
 
-func (c *ChangelogFormatter) Yaml() {
-	encoder := yaml.NewEncoder(os.Stdout)
-	_ = encoder.Encode(c.release)
+func (c *ChangelogFormatter) Json(w io.Writer) error {
+	return writeJSON(w, c.release)
 }
 
-func (c *ChangelogFormatter) Console() {
+func (c *ChangelogFormatter) Yaml(w io.Writer) error {
+	return writeYAML(w, c.release)
+}
+
+func (c *ChangelogFormatter) Console(w io.Writer) error {
 	r := c.release
+	ew := &errWriter{w: w}
 
-	fmt.Printf("Current release version\t: %s\n", r.CurrentVersion)
-	fmt.Printf("Next release version\t: %s\n", r.NextVersion)
+	fmt.Fprintf(ew, "Current release version\t: %s\n", r.CurrentVersion)
+	fmt.Fprintf(ew, "Next release version\t: %s\n", r.NextVersion)
 
-	fmt.Println("\nChangelog:")
+	fmt.Fprintln(ew, "\nChangelog:")
 
 	if len(r.Changelog) == 0 {
-		fmt.Println("No change since last release")
-		return
+		fmt.Fprintln(ew, "No change since last release")
+		return ew.err
 	}
 
-	t := NewTable(os.Stdout, "Date", "Author", "Kind", "Level", "Scope", "Title")
-	if c.colorize {
+	writeConsoleChangelog(ew, r.Changelog, c.colorize)
+	return ew.err
+}
+
+// writeConsoleChangelog writes the changes in a table, the levels are colorized
+func writeConsoleChangelog(w io.Writer, changelog []ReleaseItemDTO, colorize bool) {
+	t := NewTable(w, "Date", "Author", "Kind", "Level", "Scope", "Title")
+	if colorize {
 		t.SetColorizer(func(row []string, index int) string {
 			if index != 3 {
 				return ""
@@ -68,41 +73,60 @@ func (c *ChangelogFormatter) Console() {
 		})
 	}
 
-	for i := range r.Changelog {
+	for i := range changelog {
 		_ = t.AnalyseRow(consoleDateFormat,
-			r.Changelog[i].Author,
-			r.Changelog[i].Kind,
-			r.Changelog[i].Level,
-			r.Changelog[i].Scope,
-			r.Changelog[i].Title)
+			changelog[i].Author,
+			changelog[i].Kind,
+			changelog[i].Level,
+			changelog[i].Scope,
+			changelog[i].consoleTitle())
 	}
 
 	t.WriteHeaders()
-	for i := range r.Changelog {
-		t.WriteRow(r.Changelog[i].Date.Format(consoleDateFormat),
-			r.Changelog[i].Author,
-			r.Changelog[i].Kind,
-			r.Changelog[i].Level,
-			r.Changelog[i].Scope,
-			r.Changelog[i].Title)
+	for i := range changelog {
+		t.WriteRow(changelog[i].Date.Format(consoleDateFormat),
+			changelog[i].Author,
+			changelog[i].Kind,
+			changelog[i].Level,
+			changelog[i].Scope,
+			changelog[i].consoleTitle())
 	}
 
 }
 
-func (c *ChangelogFormatter) Template(text string) error {
-	tpl, err := template.New("Release").
-		Funcs(sprig.TxtFuncMap()).
-		Funcs(FuncMap()).
-		Parse(text)
-	if err != nil {
-		return err
-	}
+// Markdown groups the changes under headings, the title is the next version
+func (c *ChangelogFormatter) Markdown(w io.Writer) error {
+	ew := &errWriter{w: w}
+	writeMarkdownChangelog(ew, c.version(), c.release, c.links)
+	return ew.err
+}
+
+// HTML renders the release notes, titled with the project and the version
+func (c *ChangelogFormatter) HTML(w io.Writer, fragment bool) error {
+	title := strings.TrimSpace(c.release.Project + " " + c.version())
+	return writeHTML(w, title, []ReleaseDTO{*c.release}, []string{c.version()}, false, fragment, c.links)
+}
+
+func (c *ChangelogFormatter) GithubActions(w io.Writer) error {
+	return writeGithubActions(w, c.version(), c.release, c.templateOptions)
+}
 
-	return tpl.Execute(c.output, c.release)
+func (c *ChangelogFormatter) Dotenv(w io.Writer) error {
+	return writeDotenv(w, c.release)
 }
 
-func FuncMap() template.FuncMap {
-	return template.FuncMap{
-		"padRight": pad.Right,
+func (c *ChangelogFormatter) Env(w io.Writer) error {
+	return writeEnv(w, c.release)
+}
+
+// version is the name of the release, or the next version
+func (c *ChangelogFormatter) version() string {
+	if c.name != "" {
+		return c.name
 	}
+	return c.release.NextVersion
+}
+
+func (c *ChangelogFormatter) Template(w io.Writer, text string) error {
+	return c.execute(w, text, c.version(), *c.release, []ReleaseDTO{*c.release})
 }
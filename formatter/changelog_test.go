@@ -76,8 +76,7 @@ Security fixes:
 `
 
 	sb := &strings.Builder{}
-	f.output = sb
-	err := f.Template(tpl)
+	err := f.Template(sb, tpl)
 	assert.NoError(t, err)
 	assert.Equal(t, expected, sb.String())
 }
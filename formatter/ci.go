@@ -0,0 +1,111 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tauffredou/nextver/model"
+)
+
+// CIFormatter is implemented by the formatters of a single release, it writes the variables of the CI jobs and scripts
+type CIFormatter interface {
+	// GithubActions writes the step outputs, in the format of the $GITHUB_OUTPUT file
+	GithubActions(w io.Writer) error
+	// Dotenv writes the variables of a Gitlab dotenv report
+	Dotenv(w io.Writer) error
+	// Env writes shell exports
+	Env(w io.Writer) error
+}
+
+// envVariable is a variable of the dotenv and env outputs
+type envVariable struct {
+	name  string
+	value string
+}
+
+// releaseVariables are the versions of the release, the parts of the next semantic version are empty for a date version
+func releaseVariables(r *ReleaseDTO) []envVariable {
+	var major, minor, patch string
+	next := MapNextVersion(r)
+	if semver, err := model.FindSemver(r.NextVersion); err == nil {
+		major = strconv.FormatInt(semver.Major, 10)
+		minor = strconv.FormatInt(semver.Minor, 10)
+		patch = strconv.FormatInt(semver.Patch, 10)
+	}
+	return []envVariable{
+		{"NEXTVER_NEXT_VERSION", next.NextVersion},
+		{"NEXTVER_CURRENT_VERSION", next.CurrentVersion},
+		{"NEXTVER_BUMP", next.BumpLevel},
+		{"NEXTVER_MAJOR", major},
+		{"NEXTVER_MINOR", minor},
+		{"NEXTVER_PATCH", patch},
+		{"NEXTVER_PRERELEASE", next.Prerelease},
+		{"NEXTVER_REF", next.Ref},
+	}
+}
+
+const githubOutputDelimiter = "NEXTVER_EOF"
+
+// writeGithubActions writes the versions, the bump level and the markdown changelog of the release
+func writeGithubActions(w io.Writer, version string, r *ReleaseDTO, opts templateOptions) error {
+	var changelog strings.Builder
+	writeMarkdownChangelog(&changelog, version, r, opts.links)
+
+	ew := &errWriter{w: w}
+	writeGithubOutput(ew, "next_version", r.NextVersion)
+	writeGithubOutput(ew, "current_version", r.CurrentVersion)
+	writeGithubOutput(ew, "bump", r.BumpLevel())
+	writeGithubOutput(ew, "changelog", changelog.String())
+	return ew.err
+}
+
+// writeGithubOutput writes name=value, or a heredoc for a multiline value.
+// The delimiter is never a line of the value, the value cannot end the heredoc
+func writeGithubOutput(w io.Writer, name string, value string) {
+	if !strings.ContainsAny(value, "\r\n") {
+		fmt.Fprintf(w, "%s=%s\n", name, value)
+		return
+	}
+
+	delimiter := githubOutputDelimiter
+	for i := 1; strings.Contains(value, delimiter); i++ {
+		delimiter = fmt.Sprintf("%s_%d", githubOutputDelimiter, i)
+	}
+	fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", name, delimiter, strings.TrimSuffix(value, "\n"), delimiter)
+}
+
+// writeDotenv writes the release variables. A dotenv report has no multiline value: the changelog is left out
+func writeDotenv(w io.Writer, r *ReleaseDTO) error {
+	ew := &errWriter{w: w}
+	for _, v := range releaseVariables(r) {
+		fmt.Fprintf(ew, "%s=%s\n", v.name, dotenvValue(v.value))
+	}
+	return ew.err
+}
+
+// dotenvValue keeps the value on its line
+func dotenvValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+// writeEnv writes the release variables as shell exports, to be evaluated by a script
+func writeEnv(w io.Writer, r *ReleaseDTO) error {
+	ew := &errWriter{w: w}
+	for _, v := range releaseVariables(r) {
+		fmt.Fprintf(ew, "export %s=%s\n", v.name, shellQuote(v.value))
+	}
+	return ew.err
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./:@%+=,-]+$`)
+
+// shellQuote quotes the value for a POSIX shell: the single quotes keep every character but the single quote
+func shellQuote(value string) string {
+	if shellSafe.MatchString(value) {
+		return value
+	}
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,97 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+)
+
+func TestChangelogFormatter_GithubActions(t *testing.T) {
+	f := NewChangelogFormatter(templateRelease(), false)
+	f.SetLinks(model.Links{Issue: "https://github.com/owner/repo/issues/{number}"})
+
+	sb := &strings.Builder{}
+	require.NoError(t, f.GithubActions(sb))
+	assert.Equal(t, `next_version=v1.0.0
+current_version=v0.1.0
+bump=MAJOR
+changelog<<NEXTVER_EOF
+## v1.0.0
+
+### Breaking Changes
+
+- **api:** rework the api
+
+### Fixes
+
+- check the token ([#12](https://github.com/tauffredou/nextver/pull/12))
+NEXTVER_EOF
+`, sb.String())
+}
+
+func TestWriteGithubOutput_delimiterInValue(t *testing.T) {
+	sb := &strings.Builder{}
+	writeGithubOutput(sb, "changelog", "a\nNEXTVER_EOF\nNEXTVER_EOF_1\nb")
+	assert.Equal(t, "changelog<<NEXTVER_EOF_2\na\nNEXTVER_EOF\nNEXTVER_EOF_1\nb\nNEXTVER_EOF_2\n", sb.String())
+}
+
+func TestSimpleFormatter_Dotenv(t *testing.T) {
+	r := templateRelease()
+	f := &SimpleFormatter{Value: MapNextVersion(r), Release: r}
+
+	sb := &strings.Builder{}
+	require.NoError(t, f.Dotenv(sb))
+	assert.Equal(t, `NEXTVER_NEXT_VERSION=v1.0.0
+NEXTVER_CURRENT_VERSION=v0.1.0
+NEXTVER_BUMP=MAJOR
+NEXTVER_MAJOR=1
+NEXTVER_MINOR=0
+NEXTVER_PATCH=0
+NEXTVER_PRERELEASE=
+NEXTVER_REF=
+`, sb.String())
+
+	assert.Error(t, (&SimpleFormatter{Key: "key", Value: "value"}).Dotenv(sb))
+}
+
+func TestChangelogFormatter_Env(t *testing.T) {
+	r := templateRelease()
+	r.NextVersion = "v1.0.0-rc.1"
+	r.Ref = "784e8b0"
+
+	sb := &strings.Builder{}
+	require.NoError(t, NewChangelogFormatter(r, false).Env(sb))
+	assert.Equal(t, `export NEXTVER_NEXT_VERSION=v1.0.0-rc.1
+export NEXTVER_CURRENT_VERSION=v0.1.0
+export NEXTVER_BUMP=MAJOR
+export NEXTVER_MAJOR=1
+export NEXTVER_MINOR=0
+export NEXTVER_PATCH=0
+export NEXTVER_PRERELEASE=rc.1
+export NEXTVER_REF=784e8b0
+`, sb.String())
+}
+
+func TestEnv_dateVersion(t *testing.T) {
+	sb := &strings.Builder{}
+	require.NoError(t, writeEnv(sb, &ReleaseDTO{NextVersion: "release 2006-01-02-150405"}))
+	assert.Contains(t, sb.String(), "export NEXTVER_NEXT_VERSION='release 2006-01-02-150405'\n")
+	assert.Contains(t, sb.String(), "export NEXTVER_MAJOR=''\n")
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"v1.0.0":       "v1.0.0",
+		"":             "''",
+		"a b":          "'a b'",
+		"$(rm -rf /)":  "'$(rm -rf /)'",
+		"it's":         `'it'\''s'`,
+		"`id`;\n\"x\"": "'`id`;\n\"x\"'",
+	}
+	for value, expected := range tests {
+		assert.Equal(t, expected, shellQuote(value), value)
+	}
+}
@@ -1,8 +1,11 @@
 package formatter
 
 import (
+	"fmt"
 	"strings"
 	"time"
+
+	"github.com/tauffredou/nextver/model"
 )
 
 type ChangeLevel string
@@ -16,6 +19,24 @@ type ReleaseItemDTO struct {
 	Level  string    `json:"level"`
 	Author string    `json:"author"`
 	Date   time.Time `json:"date"`
+	// Section is given by the labels of the pull request
+	Section string `json:"section,omitempty"`
+	// PullRequest is set when the changelog is built from the pull requests
+	PullRequest *PullRequestDTO `json:"pull_request,omitempty"`
+}
+
+type PullRequestDTO struct {
+	Number int      `json:"number"`
+	URL    string   `json:"url"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// consoleTitle suffixes the title with the pull request number
+func (i *ReleaseItemDTO) consoleTitle() string {
+	if i.PullRequest == nil {
+		return i.Title
+	}
+	return fmt.Sprintf("%s (#%d)", i.Title, i.PullRequest.Number)
 }
 
 type ReleaseDTO struct {
@@ -27,6 +48,17 @@ type ReleaseDTO struct {
 	VersionPattern string           `json:"version_pattern"`
 }
 
+// BumpLevel is the highest level of the changes, empty without change to release
+func (r *ReleaseDTO) BumpLevel() string {
+	levels := []string{model.ChangeLevelMajor, model.ChangeLevelMinor, model.ChangeLevelPatch}
+	for _, level := range levels {
+		if r.HasChanges(level) {
+			return level
+		}
+	}
+	return ""
+}
+
 func (r *ReleaseDTO) HasChanges(level string) bool {
 	return len(r.ChangesByLevel(level)) > 0
 }
@@ -41,3 +73,32 @@ func (r *ReleaseDTO) ChangesByLevel(level string) []ReleaseItemDTO {
 	}
 	return res
 }
+
+// NextVersionDTO is the next version of a release, with what made it
+type NextVersionDTO struct {
+	NextVersion    string `json:"next_version" yaml:"next_version"`
+	CurrentVersion string `json:"current_version" yaml:"current_version"`
+	// BumpLevel is the level of the release: MAJOR, MINOR, PATCH, or empty without change
+	BumpLevel string `json:"bump_level" yaml:"bump_level"`
+	// Prerelease is the pre-release identifier of the next version, empty for a final version
+	Prerelease string `json:"prerelease" yaml:"prerelease"`
+	Ref        string `json:"ref" yaml:"ref"`
+}
+
+func (v NextVersionDTO) String() string {
+	return v.NextVersion
+}
+
+func MapNextVersion(r *ReleaseDTO) NextVersionDTO {
+	res := NextVersionDTO{
+		NextVersion:    r.NextVersion,
+		CurrentVersion: r.CurrentVersion,
+		BumpLevel:      r.BumpLevel(),
+		Ref:            r.Ref,
+	}
+	// a date version has no pre-release
+	if semver, err := model.FindSemver(r.NextVersion); err == nil {
+		res.Prerelease = semver.Prerelease
+	}
+	return res
+}
@@ -1,36 +1,79 @@
 package formatter
 
 import (
+	"encoding/json"
+	"io"
+
 	"github.com/tauffredou/nextver/model"
+	"gopkg.in/yaml.v2"
 )
 
 const consoleDateFormat = "06/01/02 15:04"
 
+// Formatter writes the result of a command in the output formats
 type Formatter interface {
-	Json()
-	Yaml()
-	Console()
-	Template(text string) error
+	Json(w io.Writer) error
+	Yaml(w io.Writer) error
+	Console(w io.Writer) error
+	Template(w io.Writer, text string) error
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeYAML(w io.Writer, v interface{}) error {
+	encoder := yaml.NewEncoder(w)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// errWriter keeps the first write error, the writes after it are ignored
+type errWriter struct {
+	w   io.Writer
+	err error
 }
 
-func MapReleases(items []model.Release) []ReleaseDTO {
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
+}
+
+func MapReleases(items []model.Release) ([]ReleaseDTO, error) {
 	res := make([]ReleaseDTO, len(items))
 	for i := range items {
-		res[i] = MapRelease(&items[i])
+		r, err := MapRelease(&items[i])
+		if err != nil {
+			return nil, err
+		}
+		res[i] = r
 	}
-	return res
+	return res, nil
 }
 
-func MapRelease(release *model.Release) ReleaseDTO {
+// MapRelease fails when the next version cannot be calculated
+func MapRelease(release *model.Release) (ReleaseDTO, error) {
+	nextVersion, err := release.NextVersion()
+	if err != nil {
+		return ReleaseDTO{}, err
+	}
 
 	return ReleaseDTO{
 		Project:        release.Project,
 		Changelog:      mapReleaseItem(release.Changelog),
-		NextVersion:    release.MustNextVersion(),
+		NextVersion:    nextVersion,
 		CurrentVersion: release.CurrentVersion,
 		Ref:            release.Ref,
 		VersionPattern: release.VersionPattern,
-	}
+	}, nil
 }
 
 func mapReleaseItem(items []model.ReleaseItem) []ReleaseItemDTO {
@@ -38,14 +81,18 @@ func mapReleaseItem(items []model.ReleaseItem) []ReleaseItemDTO {
 	for i := range items {
 		item := items[i]
 		res[i] = ReleaseItemDTO{
-			ID:     item.ID,
-			Kind:   item.Kind,
-			Level:  item.LevelName(),
-			Title:  item.Title,
-			Scope:  item.Scope,
-			Detail: item.Detail,
-			Date:   item.Date,
-			Author: item.Author,
+			ID:      item.ID,
+			Kind:    item.Kind,
+			Level:   item.LevelName(),
+			Title:   item.Title,
+			Scope:   item.Scope,
+			Detail:  item.Detail,
+			Date:    item.Date,
+			Author:  item.Author,
+			Section: item.Section,
+		}
+		if pr := item.PullRequest; pr != nil {
+			res[i].PullRequest = &PullRequestDTO{Number: pr.Number, URL: pr.URL, Labels: pr.Labels}
 		}
 	}
 	return res
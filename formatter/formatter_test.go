@@ -0,0 +1,74 @@
+package formatter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+)
+
+func TestMapRelease(t *testing.T) {
+	r := &model.Release{
+		Project:        "test",
+		CurrentVersion: "v1.0.0",
+		VersionPattern: "vSEMVER",
+		Changelog:      []model.ReleaseItem{model.NewReleaseItem("abc", "tauf", time.Now(), "feat: feature")},
+	}
+
+	actual, err := MapRelease(r)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", actual.NextVersion)
+	assert.Equal(t, model.ChangeLevelMinor, actual.Changelog[0].Level)
+}
+
+func TestMapRelease_unknownPattern(t *testing.T) {
+	_, err := MapRelease(&model.Release{CurrentVersion: "v1.0.0", VersionPattern: "unknown"})
+	assert.Error(t, err)
+
+	_, err = MapReleases([]model.Release{{CurrentVersion: "v1.0.0", VersionPattern: "unknown"}})
+	assert.Error(t, err)
+}
+
+func TestMapRelease_pullRequest(t *testing.T) {
+	pr := model.PullRequest{Number: 3, URL: "https://github.com/owner/repo/pull/3", Labels: []string{"enhancement"}}
+	r := &model.Release{
+		CurrentVersion: "v1.0.0",
+		VersionPattern: "vSEMVER",
+		Changelog:      []model.ReleaseItem{model.NewPullRequestItem("abc", "tauf", time.Now(), "Export the changelog", "", pr, model.DefaultLabelMapping)},
+	}
+
+	actual, err := MapRelease(r)
+	require.NoError(t, err)
+	assert.Equal(t, &PullRequestDTO{Number: 3, URL: pr.URL, Labels: pr.Labels}, actual.Changelog[0].PullRequest)
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestFormatters_writeError(t *testing.T) {
+	formatters := map[string]Formatter{
+		"changelog": NewChangelogFormatter(&ReleaseDTO{NextVersion: "v1.0.0"}, false),
+		"releases":  NewReleasesFormatter([]ReleaseDTO{{CurrentVersion: "v1.0.0"}}),
+		"simple":    &SimpleFormatter{Key: "next-version", Value: "v1.0.0"},
+	}
+	for name, f := range formatters {
+		t.Run(name, func(t *testing.T) {
+			assert.EqualError(t, f.Console(failingWriter{}), "disk full")
+			assert.EqualError(t, f.Json(failingWriter{}), "disk full")
+			assert.Error(t, f.Yaml(failingWriter{}))
+			assert.EqualError(t, f.Template(failingWriter{}, "text"), "disk full")
+		})
+	}
+}
+
+func TestChangelogFormatter_Yaml(t *testing.T) {
+	sb := &strings.Builder{}
+	require.NoError(t, NewChangelogFormatter(&ReleaseDTO{CurrentVersion: "v1.0.0"}, false).Yaml(sb))
+	assert.Contains(t, sb.String(), "currentversion: v1.0.0\n")
+}
@@ -0,0 +1,201 @@
+package formatter
+
+import (
+	"html/template"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/tauffredou/nextver/model"
+)
+
+// HTMLFormatter is implemented by the formatters having an html output,
+// a standalone page or a fragment to insert in a page
+type HTMLFormatter interface {
+	HTML(w io.Writer, fragment bool) error
+}
+
+// htmlPage is the data of htmlTemplate
+type htmlPage struct {
+	Title    string
+	Fragment bool
+	// List lists the releases without their changes
+	List     bool
+	Releases []htmlRelease
+}
+
+type htmlRelease struct {
+	Version   string
+	Ref       string
+	CommitURL string
+	Sections  []htmlSection
+}
+
+type htmlSection struct {
+	Title string
+	Class string
+	Items []htmlItem
+}
+
+type htmlItem struct {
+	Scope string
+	// Title is split in texts and links to the issues
+	Title       []htmlText
+	Author      string
+	Initials    string
+	AvatarURL   string
+	PullRequest *PullRequestDTO
+	Ref         string
+	CommitURL   string
+}
+
+type htmlText struct {
+	Text string
+	URL  string
+}
+
+var htmlClassReplacer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// writeHTML renders the releases, with their changes unless list is set
+func writeHTML(w io.Writer, title string, releases []ReleaseDTO, versions []string, list bool, fragment bool, links model.Links) error {
+	page := htmlPage{Title: title, Fragment: fragment, List: list}
+	for i := range releases {
+		r := &releases[i]
+		release := htmlRelease{
+			Version:   versions[i],
+			Ref:       shortRef(r.Ref),
+			CommitURL: links.CommitURL(r.Ref),
+		}
+		if !list {
+			release.Sections = htmlSections(r.Changelog, links)
+		}
+		page.Releases = append(page.Releases, release)
+	}
+	return htmlTemplate.Execute(w, page)
+}
+
+// htmlSections groups the changes like the markdown changelog
+func htmlSections(changes []ReleaseItemDTO, links model.Links) []htmlSection {
+	titles, sections := groupMarkdownSections(changes)
+	res := make([]htmlSection, len(titles))
+	for i, title := range titles {
+		res[i] = htmlSection{
+			Title: title,
+			Class: strings.Trim(htmlClassReplacer.ReplaceAllString(strings.ToLower(title), "-"), "-"),
+		}
+		for _, c := range sections[title] {
+			res[i].Items = append(res[i].Items, htmlChange(&c, links))
+		}
+	}
+	return res
+}
+
+// htmlChange shows the avatar of the author of a pull request, the author is a login. The initials are shown otherwise
+func htmlChange(c *ReleaseItemDTO, links model.Links) htmlItem {
+	item := htmlItem{
+		Scope:       c.Scope,
+		Title:       htmlTitle(c.Title, links),
+		Author:      c.Author,
+		Initials:    initials(c.Author),
+		PullRequest: c.PullRequest,
+		Ref:         shortRef(c.ID),
+		CommitURL:   links.CommitURL(c.ID),
+	}
+	if c.PullRequest != nil {
+		item.AvatarURL = links.AvatarURL(c.Author)
+	}
+	return item
+}
+
+// htmlTitle links the issue references of the title
+func htmlTitle(title string, links model.Links) []htmlText {
+	if links.Issue == "" {
+		return []htmlText{{Text: title}}
+	}
+	var res []htmlText
+	last := 0
+	for _, m := range issueReference.FindAllStringSubmatchIndex(title, -1) {
+		// m[4]:m[5] is the number, after the #
+		number := title[m[4]:m[5]]
+		res = append(res, htmlText{Text: title[last : m[4]-1]}, htmlText{Text: "#" + number, URL: links.IssueURL(number)})
+		last = m[5]
+	}
+	return append(res, htmlText{Text: title[last:]})
+}
+
+// initials are the first letters of the first two words of the name
+func initials(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var res []rune
+	for _, word := range words {
+		res = append(res, unicode.ToUpper([]rune(word)[0]))
+		if len(res) == 2 {
+			break
+		}
+	}
+	return string(res)
+}
+
+var htmlTemplate = template.Must(template.New("html").Parse(htmlTemplateText + htmlRefTemplate))
+
+// htmlRefTemplate links the commit when its page is known
+const htmlRefTemplate = `{{ define "ref" }}{{ if .CommitURL }}<a class="nextver-ref" href="{{ .CommitURL }}">{{ .Ref }}</a>{{ else }}<span class="nextver-ref">{{ .Ref }}</span>{{ end }}{{ end }}`
+
+const htmlTemplateText = `{{ if not .Fragment -}}
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 50em; margin: 2em auto; color: #24292f; }
+.nextver-author { display: inline-block; width: 1.6em; height: 1.6em; line-height: 1.6em; border-radius: 50%; background: #d0d7de; text-align: center; font-size: .75em; vertical-align: middle; overflow: hidden; }
+.nextver-author img { width: 100%; height: 100%; }
+.nextver-ref { font-family: monospace; }
+.nextver-breaking-changes h3 { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+{{ end -}}
+<div class="nextver-release-notes">
+{{- if .List }}
+<ul>
+{{- range .Releases }}
+<li>{{ .Version }}{{ if .Ref }} ({{ template "ref" . }}){{ end }}</li>
+{{- end }}
+</ul>
+{{- else }}
+{{- range .Releases }}
+<section class="nextver-release">
+<h2>{{ .Version }}</h2>
+{{- range .Sections }}
+<section class="nextver-{{ .Class }}">
+<h3>{{ .Title }}</h3>
+<ul>
+{{- range .Items }}
+<li>
+{{- if .AvatarURL }}<span class="nextver-author" title="{{ .Author }}"><img src="{{ .AvatarURL }}" alt="{{ .Initials }}"></span>
+{{- else if .Initials }}<span class="nextver-author" title="{{ .Author }}">{{ .Initials }}</span>{{ end }}
+{{- if .Scope }} <strong>{{ .Scope }}:</strong>{{ end }} {{ range .Title }}{{ if .URL }}<a href="{{ .URL }}">{{ .Text }}</a>{{ else }}{{ .Text }}{{ end }}{{ end }}
+{{- with .PullRequest }} (<a href="{{ .URL }}">#{{ .Number }}</a>){{ end }}
+{{- if .Ref }} ({{ template "ref" . }}){{ end -}}
+</li>
+{{- end }}
+</ul>
+</section>
+{{- else }}
+<p>No change since last release</p>
+{{- end }}
+</section>
+{{- end }}
+{{- end }}
+</div>
+{{- if not .Fragment }}
+</body>
+</html>
+{{- end }}
+`
@@ -0,0 +1,101 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+)
+
+var htmlLinks = model.Links{
+	Commit: "https://github.com/owner/repo/commit/{ref}",
+	Issue:  "https://github.com/owner/repo/issues/{number}",
+	Avatar: "https://github.com/{login}.png",
+}
+
+func TestChangelogFormatter_HTML_fragment(t *testing.T) {
+	r := templateRelease()
+	r.Changelog[0].ID = "1c23cc36d1383b82198af6ee04fe44b820b6a550"
+	r.Changelog[0].Author = "Tom Auffret"
+	r.Changelog[0].Title = "rework the api, see #3"
+	f := NewChangelogFormatter(r, false)
+	f.SetLinks(htmlLinks)
+
+	sb := &strings.Builder{}
+	require.NoError(t, f.HTML(sb, true))
+	assert.Equal(t, `<div class="nextver-release-notes">
+<section class="nextver-release">
+<h2>v1.0.0</h2>
+<section class="nextver-breaking-changes">
+<h3>Breaking Changes</h3>
+<ul>
+<li><span class="nextver-author" title="Tom Auffret">TA</span> <strong>api:</strong> rework the api, see <a href="https://github.com/owner/repo/issues/3">#3</a> (<a class="nextver-ref" href="https://github.com/owner/repo/commit/1c23cc36d1383b82198af6ee04fe44b820b6a550">1c23cc3</a>)</li>
+</ul>
+</section>
+<section class="nextver-fixes">
+<h3>Fixes</h3>
+<ul>
+<li><span class="nextver-author" title="bob"><img src="https://github.com/bob.png" alt="B"></span> check the token (<a href="https://github.com/tauffredou/nextver/pull/12">#12</a>)</li>
+</ul>
+</section>
+</section>
+</div>
+`, sb.String())
+}
+
+func TestChangelogFormatter_HTML_page(t *testing.T) {
+	f := NewChangelogFormatter(&ReleaseDTO{Project: "nextver", NextVersion: "v1.0.0"}, false)
+
+	sb := &strings.Builder{}
+	require.NoError(t, f.HTML(sb, false))
+	assert.True(t, strings.HasPrefix(sb.String(), "<!DOCTYPE html>\n"))
+	assert.Contains(t, sb.String(), "<title>nextver v1.0.0</title>")
+	assert.Contains(t, sb.String(), "<p>No change since last release</p>")
+	assert.True(t, strings.HasSuffix(sb.String(), "</html>\n"))
+}
+
+func TestChangelogFormatter_HTML_escaping(t *testing.T) {
+	r := &ReleaseDTO{NextVersion: "v1.0.0", Changelog: []ReleaseItemDTO{{
+		Title:       `<script>alert("x")</script> & co`,
+		Author:      `"><img>`,
+		Level:       model.ChangeLevelPatch,
+		PullRequest: &PullRequestDTO{Number: 1, URL: "javascript:alert(1)"},
+	}}}
+	f := NewChangelogFormatter(r, false)
+	f.SetLinks(model.Links{Avatar: "https://github.com/{login}.png"})
+
+	sb := &strings.Builder{}
+	require.NoError(t, f.HTML(sb, true))
+	assert.NotContains(t, sb.String(), "<script>")
+	assert.NotContains(t, sb.String(), "javascript:")
+	assert.NotContains(t, sb.String(), `"><img>`)
+	assert.Contains(t, sb.String(), "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; co")
+}
+
+func TestReleasesFormatter_HTML(t *testing.T) {
+	f := NewReleasesFormatter([]ReleaseDTO{{Project: "nextver", CurrentVersion: "v1.0.0", Ref: "1c23cc36d1383b82198af6ee04fe44b820b6a550"}})
+	f.SetLinks(htmlLinks)
+
+	sb := &strings.Builder{}
+	require.NoError(t, f.HTML(sb, true))
+	assert.Equal(t, `<div class="nextver-release-notes">
+<ul>
+<li>v1.0.0 (<a class="nextver-ref" href="https://github.com/owner/repo/commit/1c23cc36d1383b82198af6ee04fe44b820b6a550">1c23cc3</a>)</li>
+</ul>
+</div>
+`, sb.String())
+
+	sb.Reset()
+	require.NoError(t, NewReleasesChangelogFormatter(testReleasesChangelog(), false).HTML(sb, false))
+	assert.Contains(t, sb.String(), "<h2>v1.1.0</h2>")
+	assert.Contains(t, sb.String(), "<h2>v1.0.0</h2>")
+}
+
+func TestInitials(t *testing.T) {
+	assert.Equal(t, "TA", initials("Tom Auffret"))
+	assert.Equal(t, "T", initials("tauf"))
+	assert.Equal(t, "JD", initials("john.doe-smith"))
+	assert.Equal(t, "", initials(""))
+}
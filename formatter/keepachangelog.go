@@ -0,0 +1,214 @@
+package formatter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tauffredou/nextver/model"
+)
+
+// DefaultKeepAChangelogHeader starts a new changelog file
+const DefaultKeepAChangelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.0.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+`
+
+const keepAChangelogDateFormat = "2006-01-02"
+
+// KeepAChangelogUnreleased is the section of the changes not released yet
+const KeepAChangelogUnreleased = "Unreleased"
+
+// keepAChangelogSections are the sections of a version, in order
+var keepAChangelogSections = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+// keepAChangelogKinds maps the kinds of change to the sections.
+// The changes of another kind are left out unless they are breaking changes
+var keepAChangelogKinds = map[string]string{
+	"feat":      "Added",
+	"perf":      "Changed",
+	"refactor":  "Changed",
+	"deprecate": "Deprecated",
+	"remove":    "Removed",
+	"revert":    "Removed",
+	"fix":       "Fixed",
+	"security":  "Security",
+}
+
+var (
+	versionHeading = regexp.MustCompile(`^## \[?([^\]\s]+)\]?`)
+	linkReference  = regexp.MustCompile(`^\[[^\]]+\]: `)
+)
+
+// KeepAChangelog is a CHANGELOG.md file in the Keep a Changelog format (https://keepachangelog.com).
+// The text around the versions written by nextver is kept as is
+type KeepAChangelog struct {
+	header   string
+	versions []keepAChangelogVersion
+	// footer holds the link references closing the file
+	footer string
+}
+
+type keepAChangelogVersion struct {
+	name string
+	text string
+}
+
+// NewKeepAChangelog creates an empty changelog
+func NewKeepAChangelog() *KeepAChangelog {
+	return &KeepAChangelog{header: DefaultKeepAChangelogHeader}
+}
+
+// ParseKeepAChangelog reads a changelog, the versions are the level 2 headings
+func ParseKeepAChangelog(text string) *KeepAChangelog {
+	lines := strings.SplitAfter(text, "\n")
+
+	end := len(lines)
+	for end > 0 && (linkReference.MatchString(lines[end-1]) || strings.TrimSpace(lines[end-1]) == "") {
+		end--
+	}
+	for end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+		end++
+	}
+
+	c := &KeepAChangelog{footer: strings.Join(lines[end:], "")}
+	var current *keepAChangelogVersion
+	for _, line := range lines[:end] {
+		if m := versionHeading.FindStringSubmatch(line); m != nil {
+			c.versions = append(c.versions, keepAChangelogVersion{name: m[1]})
+			current = &c.versions[len(c.versions)-1]
+		}
+		if current == nil {
+			c.header += line
+		} else {
+			current.text += line
+		}
+	}
+	return c
+}
+
+// SetRelease writes the section of a version: an existing section is replaced,
+// a new one comes first, after Unreleased
+func (c *KeepAChangelog) SetRelease(version string, date time.Time, r *ReleaseDTO, links model.Links) {
+	section := keepAChangelogVersion{name: version, text: keepAChangelogSection(version, date, r, links)}
+
+	for i := range c.versions {
+		if sameVersion(c.versions[i].name, version) {
+			c.versions[i] = section
+			return
+		}
+	}
+
+	i := 0
+	for i < len(c.versions) && strings.EqualFold(c.versions[i].name, KeepAChangelogUnreleased) {
+		i++
+	}
+	c.versions = append(c.versions, keepAChangelogVersion{})
+	copy(c.versions[i+1:], c.versions[i:])
+	c.versions[i] = section
+}
+
+// SetUnreleased writes the changes not released yet in the Unreleased section, which comes first.
+// Their version is only known once the release is tagged
+func (c *KeepAChangelog) SetUnreleased(r *ReleaseDTO, links model.Links) {
+	c.SetRelease(KeepAChangelogUnreleased, time.Time{}, r, links)
+}
+
+// HasRelease tells if the changelog has a section for the version
+func (c *KeepAChangelog) HasRelease(version string) bool {
+	for i := range c.versions {
+		if sameVersion(c.versions[i].name, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// String writes the header, the versions and the link references separated by a blank line
+func (c *KeepAChangelog) String() string {
+	parts := make([]string, 0, len(c.versions)+2)
+	for _, p := range append(append([]string{c.header}, c.versionTexts()...), c.footer) {
+		if p = strings.TrimRight(p, "\n"); strings.TrimSpace(p) != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+func (c *KeepAChangelog) versionTexts() []string {
+	res := make([]string, len(c.versions))
+	for i := range c.versions {
+		res[i] = c.versions[i].text
+	}
+	return res
+}
+
+func keepAChangelogSection(version string, date time.Time, r *ReleaseDTO, links model.Links) string {
+	var sb strings.Builder
+	sb.WriteString("## [" + version + "]")
+	if !date.IsZero() {
+		sb.WriteString(" - " + date.Format(keepAChangelogDateFormat))
+	}
+	sb.WriteString("\n")
+
+	sections := make(map[string][]string)
+	for i := range r.Changelog {
+		item := &r.Changelog[i]
+		section, ok := keepAChangelogSectionOf(item)
+		if !ok {
+			continue
+		}
+		line := markdownItem(item, links)
+		if item.Level == model.ChangeLevelMajor {
+			line = "**BREAKING** " + line
+		}
+		sections[section] = append(sections[section], line)
+	}
+
+	for _, s := range keepAChangelogSections {
+		if len(sections[s]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n### %s\n\n", s)
+		for _, line := range sections[s] {
+			sb.WriteString("- " + line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// keepAChangelogSectionOf finds the section of a change: the section of its labels when it is a Keep a Changelog one,
+// then the section of its kind. The other breaking changes are changes
+func keepAChangelogSectionOf(item *ReleaseItemDTO) (string, bool) {
+	for _, s := range keepAChangelogSections {
+		if strings.EqualFold(item.Section, s) {
+			return s, true
+		}
+	}
+	if s, ok := keepAChangelogKinds[item.Kind]; ok {
+		return s, true
+	}
+	if item.Level == model.ChangeLevelMajor {
+		return "Changed", true
+	}
+	return "", false
+}
+
+// ReleaseDate is the date of the last change of the release, zero without change
+func ReleaseDate(r *ReleaseDTO) time.Time {
+	var date time.Time
+	for _, item := range r.Changelog {
+		if item.Date.After(date) {
+			date = item.Date
+		}
+	}
+	return date
+}
+
+func sameVersion(a string, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v"))
+}
@@ -0,0 +1,181 @@
+package formatter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tauffredou/nextver/model"
+)
+
+var testKeepAChangelogDate = time.Date(2019, 6, 27, 0, 0, 0, 0, time.UTC)
+
+const testKeepAChangelog = `# Changelog
+
+Written by hand.
+
+## [Unreleased]
+
+- work in progress
+
+## [v1.0.0] - 2019-06-01
+
+### Added
+
+- first version
+
+[Unreleased]: https://github.com/owner/repo/compare/v1.0.0...HEAD
+[v1.0.0]: https://github.com/owner/repo/releases/tag/v1.0.0
+`
+
+func testKeepAChangelogRelease() *ReleaseDTO {
+	return &ReleaseDTO{
+		NextVersion: "v1.1.0",
+		Changelog: []ReleaseItemDTO{
+			{ID: "c1", Kind: "feat", Scope: "api", Title: "add the export", Level: model.ChangeLevelMinor},
+			{ID: "c2", Kind: "fix", Title: "check the token", Level: model.ChangeLevelPatch},
+			{ID: "c3", Kind: "chore", Title: "update the dependencies"},
+			{ID: "c4", Kind: "chore", Title: "drop go 1.11", Level: model.ChangeLevelMajor},
+			{ID: "c5", Title: "harden the api", Section: "security"},
+		},
+	}
+}
+
+func TestKeepAChangelog_SetRelease(t *testing.T) {
+	c := ParseKeepAChangelog(testKeepAChangelog)
+	c.SetRelease("v1.1.0", testKeepAChangelogDate, testKeepAChangelogRelease(), model.Links{})
+
+	expected := `# Changelog
+
+Written by hand.
+
+## [Unreleased]
+
+- work in progress
+
+## [v1.1.0] - 2019-06-27
+
+### Added
+
+- **api:** add the export (c1)
+
+### Changed
+
+- **BREAKING** drop go 1.11 (c4)
+
+### Fixed
+
+- check the token (c2)
+
+### Security
+
+- harden the api (c5)
+
+## [v1.0.0] - 2019-06-01
+
+### Added
+
+- first version
+
+[Unreleased]: https://github.com/owner/repo/compare/v1.0.0...HEAD
+[v1.0.0]: https://github.com/owner/repo/releases/tag/v1.0.0
+`
+	assert.Equal(t, expected, c.String())
+}
+
+func TestKeepAChangelog_SetRelease_idempotent(t *testing.T) {
+	c := ParseKeepAChangelog(testKeepAChangelog)
+	c.SetRelease("v1.1.0", testKeepAChangelogDate, testKeepAChangelogRelease(), model.Links{})
+	once := c.String()
+
+	c = ParseKeepAChangelog(once)
+	c.SetRelease("v1.1.0", testKeepAChangelogDate, testKeepAChangelogRelease(), model.Links{})
+	assert.Equal(t, once, c.String())
+}
+
+func TestKeepAChangelog_SetRelease_replace(t *testing.T) {
+	c := ParseKeepAChangelog(testKeepAChangelog)
+	c.SetRelease("1.0.0", time.Time{}, &ReleaseDTO{Changelog: []ReleaseItemDTO{{Kind: "fix", Title: "regenerated"}}}, model.Links{})
+
+	assert.Equal(t, `# Changelog
+
+Written by hand.
+
+## [Unreleased]
+
+- work in progress
+
+## [1.0.0]
+
+### Fixed
+
+- regenerated
+
+[Unreleased]: https://github.com/owner/repo/compare/v1.0.0...HEAD
+[v1.0.0]: https://github.com/owner/repo/releases/tag/v1.0.0
+`, c.String())
+}
+
+func TestKeepAChangelog_SetUnreleased(t *testing.T) {
+	c := ParseKeepAChangelog(testKeepAChangelog)
+	c.SetUnreleased(&ReleaseDTO{Changelog: []ReleaseItemDTO{{Kind: "fix", Title: "pending"}}}, model.Links{})
+
+	assert.Equal(t, `# Changelog
+
+Written by hand.
+
+## [Unreleased]
+
+### Fixed
+
+- pending
+
+## [v1.0.0] - 2019-06-01
+
+### Added
+
+- first version
+
+[Unreleased]: https://github.com/owner/repo/compare/v1.0.0...HEAD
+[v1.0.0]: https://github.com/owner/repo/releases/tag/v1.0.0
+`, c.String())
+
+	c = NewKeepAChangelog()
+	c.SetRelease("v1.0.0", time.Time{}, &ReleaseDTO{}, model.Links{})
+	c.SetUnreleased(&ReleaseDTO{}, model.Links{})
+	assert.Equal(t, DefaultKeepAChangelogHeader+"\n## [Unreleased]\n\n## [v1.0.0]\n", c.String())
+}
+
+func TestKeepAChangelog_HasRelease(t *testing.T) {
+	c := ParseKeepAChangelog(testKeepAChangelog)
+	assert.True(t, c.HasRelease("v1.0.0"))
+	assert.True(t, c.HasRelease("1.0.0"))
+	assert.True(t, c.HasRelease("unreleased"))
+	assert.False(t, c.HasRelease("v1.1.0"))
+}
+
+func TestKeepAChangelog_new(t *testing.T) {
+	c := NewKeepAChangelog()
+	c.SetRelease("v1.0.0", time.Time{}, &ReleaseDTO{Changelog: []ReleaseItemDTO{{Kind: "feat", Title: "first"}}}, model.Links{})
+	c.SetRelease("v1.1.0", time.Time{}, &ReleaseDTO{Changelog: []ReleaseItemDTO{{Kind: "feat", Title: "second"}}}, model.Links{})
+
+	assert.Equal(t, DefaultKeepAChangelogHeader+`
+## [v1.1.0]
+
+### Added
+
+- second
+
+## [v1.0.0]
+
+### Added
+
+- first
+`, c.String())
+}
+
+func TestReleaseDate(t *testing.T) {
+	r := &ReleaseDTO{Changelog: []ReleaseItemDTO{{Date: testKeepAChangelogDate}, {Date: testKeepAChangelogDate.Add(-time.Hour)}}}
+	assert.Equal(t, testKeepAChangelogDate, ReleaseDate(r))
+	assert.True(t, ReleaseDate(&ReleaseDTO{}).IsZero())
+}
@@ -0,0 +1,153 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/tauffredou/nextver/model"
+)
+
+// MarkdownFormatter is implemented by the formatters having a markdown output
+type MarkdownFormatter interface {
+	Markdown(w io.Writer) error
+}
+
+// markdownSection is a heading of the markdown changelog
+type markdownSection struct {
+	title string
+	match func(item *ReleaseItemDTO) bool
+}
+
+// markdownSections group the changes by level then by kind, the first matching section wins.
+// The section given by the labels of a pull request has priority
+var markdownSections = []markdownSection{
+	{"Breaking Changes", func(i *ReleaseItemDTO) bool { return i.Level == model.ChangeLevelMajor }},
+	{"Features", func(i *ReleaseItemDTO) bool { return i.Kind == "feat" || i.Level == model.ChangeLevelMinor }},
+	{"Fixes", func(i *ReleaseItemDTO) bool { return i.Kind == "fix" || i.Level == model.ChangeLevelPatch }},
+	{markdownOtherSection, func(i *ReleaseItemDTO) bool { return true }},
+}
+
+const markdownOtherSection = "Other"
+
+var (
+	issueReference   = regexp.MustCompile(`(^|[^\w&])#(\d+)\b`)
+	markdownReplacer = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`)
+)
+
+const shortRefLength = 7
+
+func writeMarkdownChangelog(w io.Writer, version string, r *ReleaseDTO, links model.Links) {
+	fmt.Fprintf(w, "## %s\n", version)
+
+	if len(r.Changelog) == 0 {
+		fmt.Fprint(w, "\nNo change since last release\n")
+		return
+	}
+
+	titles, sections := groupMarkdownSections(r.Changelog)
+	for _, title := range titles {
+		fmt.Fprintf(w, "\n### %s\n\n", title)
+		for _, item := range sections[title] {
+			fmt.Fprintf(w, "- %s\n", markdownItem(&item, links))
+		}
+	}
+}
+
+// groupMarkdownSections groups the items by section: the section of the labels if any, a default section otherwise.
+// The sections of the labels come before Other, in order of appearance
+func groupMarkdownSections(items []ReleaseItemDTO) ([]string, map[string][]ReleaseItemDTO) {
+	sections := make(map[string][]ReleaseItemDTO)
+	custom := make([]string, 0)
+	for _, item := range items {
+		title := item.Section
+		if title == "" {
+			for _, s := range markdownSections {
+				if s.match(&item) {
+					title = s.title
+					break
+				}
+			}
+		} else if _, ok := sections[title]; !ok && !isDefaultSection(title) {
+			custom = append(custom, title)
+		}
+		sections[title] = append(sections[title], item)
+	}
+
+	titles := make([]string, 0, len(sections))
+	for _, s := range markdownSections {
+		if s.title == markdownOtherSection {
+			titles = append(titles, custom...)
+		}
+		if len(sections[s.title]) > 0 {
+			titles = append(titles, s.title)
+		}
+	}
+	return titles, sections
+}
+
+func isDefaultSection(title string) bool {
+	for _, s := range markdownSections {
+		if s.title == title {
+			return true
+		}
+	}
+	return false
+}
+
+// markdownItem writes the scope, the title with the issues linked, then the pull request and the commit
+func markdownItem(item *ReleaseItemDTO, links model.Links) string {
+	var sb strings.Builder
+	if item.Scope != "" {
+		sb.WriteString("**" + markdownEscape(item.Scope) + ":** ")
+	}
+	sb.WriteString(linkIssues(markdownEscape(item.Title), links))
+
+	if pr := item.PullRequest; pr != nil {
+		sb.WriteString(" (" + markdownLink(fmt.Sprintf("#%d", pr.Number), pr.URL) + ")")
+	}
+	if item.ID != "" {
+		sb.WriteString(" (" + markdownLink(shortRef(item.ID), links.CommitURL(item.ID)) + ")")
+	}
+	return sb.String()
+}
+
+func writeMarkdownReleases(w io.Writer, releases []ReleaseDTO, links model.Links) {
+	for _, r := range releases {
+		line := "- " + r.CurrentVersion
+		if r.Ref != "" {
+			line += " (" + markdownLink(shortRef(r.Ref), links.CommitURL(r.Ref)) + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+func linkIssues(text string, links model.Links) string {
+	if links.Issue == "" {
+		return text
+	}
+	return issueReference.ReplaceAllStringFunc(text, func(s string) string {
+		m := issueReference.FindStringSubmatch(s)
+		return m[1] + markdownLink("#"+m[2], links.IssueURL(m[2]))
+	})
+}
+
+// markdownLink is the plain text when the url is unknown
+func markdownLink(text string, url string) string {
+	if url == "" {
+		return text
+	}
+	return "[" + text + "](" + url + ")"
+}
+
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
+
+func shortRef(ref string) string {
+	if len(ref) > shortRefLength {
+		return ref[:shortRefLength]
+	}
+	return ref
+}
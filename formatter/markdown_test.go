@@ -0,0 +1,106 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+)
+
+var testLinks = model.Links{
+	Commit: "https://github.com/owner/repo/commit/{ref}",
+	Issue:  "https://github.com/owner/repo/issues/{number}",
+}
+
+func TestChangelogFormatter_Markdown(t *testing.T) {
+	r := &ReleaseDTO{
+		CurrentVersion: "v0.1.0",
+		NextVersion:    "v1.0.0",
+		Changelog: []ReleaseItemDTO{
+			{ID: "1c23cc36d1383b82198af6ee04fe44b820b6a550", Kind: "feat", Scope: "api", Title: "rework the api", Level: model.ChangeLevelMajor},
+			{ID: "784e8b02254bae917a276691fd45b8256fb491e8", Kind: "fix", Title: "check the user_id (closes #12)", Level: model.ChangeLevelPatch},
+			{ID: "14c9b1a0e9edb211596be27361025ce748e28f98", Kind: "feat", Title: "export the changelog", Level: model.ChangeLevelMinor,
+				PullRequest: &PullRequestDTO{Number: 3, URL: "https://github.com/owner/repo/pull/3"}},
+			{ID: "fc8b62356ab9ba6caa61c3e82499e86f63f46062", Title: "bump dependencies", Section: "Dependencies"},
+			{ID: "236be09573257deb629ccc650245e5c616221a7f", Kind: "chore", Title: "update the readme"},
+		},
+	}
+
+	expected := `## v1.0.0
+
+### Breaking Changes
+
+- **api:** rework the api ([1c23cc3](https://github.com/owner/repo/commit/1c23cc36d1383b82198af6ee04fe44b820b6a550))
+
+### Features
+
+- export the changelog ([#3](https://github.com/owner/repo/pull/3)) ([14c9b1a](https://github.com/owner/repo/commit/14c9b1a0e9edb211596be27361025ce748e28f98))
+
+### Fixes
+
+- check the user\_id (closes [#12](https://github.com/owner/repo/issues/12)) ([784e8b0](https://github.com/owner/repo/commit/784e8b02254bae917a276691fd45b8256fb491e8))
+
+### Dependencies
+
+- bump dependencies ([fc8b623](https://github.com/owner/repo/commit/fc8b62356ab9ba6caa61c3e82499e86f63f46062))
+
+### Other
+
+- update the readme ([236be09](https://github.com/owner/repo/commit/236be09573257deb629ccc650245e5c616221a7f))
+`
+
+	sb := &strings.Builder{}
+	f := NewChangelogFormatter(r, false)
+	f.SetLinks(testLinks)
+	require.NoError(t, f.Markdown(sb))
+	assert.Equal(t, expected, sb.String())
+}
+
+func TestChangelogFormatter_Markdown_release(t *testing.T) {
+	r := &ReleaseDTO{
+		CurrentVersion: "v0.1.0",
+		NextVersion:    "v0.1.1",
+		Changelog:      []ReleaseItemDTO{{ID: "abc", Title: "see #4", Level: model.ChangeLevelPatch}},
+	}
+
+	sb := &strings.Builder{}
+	f := NewChangelogFormatter(r, false)
+	f.SetRelease("v0.1.0")
+	require.NoError(t, f.Markdown(sb))
+	assert.Equal(t, "## v0.1.0\n\n### Fixes\n\n- see #4 (abc)\n", sb.String())
+}
+
+func TestChangelogFormatter_Markdown_empty(t *testing.T) {
+	sb := &strings.Builder{}
+	f := NewChangelogFormatter(&ReleaseDTO{NextVersion: "v0.1.0"}, false)
+	require.NoError(t, f.Markdown(sb))
+	assert.Equal(t, "## v0.1.0\n\nNo change since last release\n", sb.String())
+}
+
+func TestReleasesFormatter_Markdown(t *testing.T) {
+	sb := &strings.Builder{}
+	f := NewReleasesFormatter([]ReleaseDTO{
+		{CurrentVersion: "v1.1.0", Ref: "a3240571ac4bbe857a0cfad3b988942838e758d1"},
+		{CurrentVersion: "v1.0.0"},
+	})
+	f.SetLinks(testLinks)
+	require.NoError(t, f.Markdown(sb))
+	assert.Equal(t, "- v1.1.0 ([a324057](https://github.com/owner/repo/commit/a3240571ac4bbe857a0cfad3b988942838e758d1))\n- v1.0.0\n", sb.String())
+}
+
+func TestLinkIssues(t *testing.T) {
+	assert.Equal(t, "fix [#1](https://github.com/owner/repo/issues/1), [#2](https://github.com/owner/repo/issues/2) and &#3",
+		linkIssues("fix #1, #2 and &#3", testLinks))
+}
+
+func TestReleasesFormatter_Markdown_changelog(t *testing.T) {
+	sb := &strings.Builder{}
+	f := NewReleasesChangelogFormatter([]ReleaseDTO{
+		{CurrentVersion: "v1.1.0", Changelog: []ReleaseItemDTO{{ID: "c2", Kind: "feat", Title: "second", Level: model.ChangeLevelMinor}}},
+		{CurrentVersion: "v1.0.0"},
+	}, false)
+	require.NoError(t, f.Markdown(sb))
+	assert.Equal(t, "## v1.1.0\n\n### Features\n\n- second (c2)\n\n## v1.0.0\n\nNo change since last release\n", sb.String())
+}
@@ -1,47 +1,96 @@
 package formatter
 
 import (
-	"encoding/json"
-	"gopkg.in/yaml.v2"
+	"fmt"
 	"io"
-	"os"
-	"text/template"
 )
 
 type ReleasesFormatter struct {
 	releases []ReleaseDTO
-	output   io.Writer
+	// next is the next release, given to the templates
+	next *ReleaseDTO
+	templateOptions
+	// changelog renders the changes of each release instead of the release list
+	changelog bool
+	colorize  bool
 }
 
-func (f *ReleasesFormatter) Template(text string) error {
-	tpl, err := template.New("Release").Parse(text)
-	if err != nil {
-		return err
+// SetNextRelease gives the next release to the templates
+func (f *ReleasesFormatter) SetNextRelease(next *ReleaseDTO) {
+	f.next = next
+}
+
+// Markdown lists the releases with their commit, or the changelog of each release
+func (f *ReleasesFormatter) Markdown(w io.Writer) error {
+	ew := &errWriter{w: w}
+	if !f.changelog {
+		writeMarkdownReleases(ew, f.releases, f.links)
+		return ew.err
 	}
+	for i := range f.releases {
+		if i > 0 {
+			fmt.Fprintln(ew)
+		}
+		writeMarkdownChangelog(ew, f.releases[i].CurrentVersion, &f.releases[i], f.links)
+	}
+	return ew.err
+}
 
-	return tpl.Execute(f.output, f.releases)
+// HTML lists the releases, or renders the release notes of each release
+func (f *ReleasesFormatter) HTML(w io.Writer, fragment bool) error {
+	title := "Releases"
+	if len(f.releases) > 0 && f.releases[0].Project != "" {
+		title = f.releases[0].Project + " releases"
+	}
+	versions := make([]string, len(f.releases))
+	for i := range f.releases {
+		versions[i] = f.releases[i].CurrentVersion
+	}
+	return writeHTML(w, title, f.releases, versions, !f.changelog, fragment, f.links)
+}
+
+// Template renders the template with the releases, and the next release when it is known
+func (f *ReleasesFormatter) Template(w io.Writer, text string) error {
+	var release ReleaseDTO
+	switch {
+	case f.next != nil:
+		release = *f.next
+	case len(f.releases) > 0:
+		release.Project = f.releases[0].Project
+	}
+	return f.execute(w, text, release.NextVersion, release, f.releases)
 }
 
 func NewReleasesFormatter(releases []ReleaseDTO) *ReleasesFormatter {
 	return &ReleasesFormatter{
 		releases: releases,
-		output:   os.Stdout,
 	}
 }
 
-func (f *ReleasesFormatter) Json() {
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	_ = encoder.Encode(f.releases)
+// NewReleasesChangelogFormatter renders the changelogs of several releases, the releases hold their changes
+func NewReleasesChangelogFormatter(releases []ReleaseDTO, colorize bool) *ReleasesFormatter {
+	f := NewReleasesFormatter(releases)
+	f.changelog = true
+	f.colorize = colorize
+	return f
+}
+
+func (f *ReleasesFormatter) Json(w io.Writer) error {
+	return writeJSON(w, f.releases)
 }
 
-func (f *ReleasesFormatter) Yaml() {
-	encoder := yaml.NewEncoder(os.Stdout)
-	_ = encoder.Encode(f.releases)
+func (f *ReleasesFormatter) Yaml(w io.Writer) error {
+	return writeYAML(w, f.releases)
 }
 
-func (f *ReleasesFormatter) Console() {
-	t := NewTable(os.Stdout, "ref", "release")
+func (f *ReleasesFormatter) Console(w io.Writer) error {
+	ew := &errWriter{w: w}
+	if f.changelog {
+		f.consoleChangelog(ew)
+		return ew.err
+	}
+
+	t := NewTable(ew, "ref", "release")
 	for _, v := range f.releases {
 		_ = t.AnalyseRow(v.Ref, v.CurrentVersion)
 	}
@@ -50,5 +99,19 @@ func (f *ReleasesFormatter) Console() {
 	for _, v := range f.releases {
 		t.WriteRow(v.Ref, v.CurrentVersion)
 	}
+	return ew.err
+}
 
+func (f *ReleasesFormatter) consoleChangelog(w io.Writer) {
+	for i, r := range f.releases {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Release %s:\n", r.CurrentVersion)
+		if len(r.Changelog) == 0 {
+			fmt.Fprintln(w, "No change")
+			continue
+		}
+		writeConsoleChangelog(w, r.Changelog, f.colorize)
+	}
 }
@@ -0,0 +1,41 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+)
+
+func testReleasesChangelog() []ReleaseDTO {
+	return []ReleaseDTO{
+		{CurrentVersion: "v1.1.0", Changelog: []ReleaseItemDTO{
+			{Kind: "feat", Title: "second", Level: model.ChangeLevelMinor, Author: "tauf"},
+		}},
+		{CurrentVersion: "v1.0.0"},
+	}
+}
+
+func TestReleasesFormatter_Template_changelog(t *testing.T) {
+	sb := &strings.Builder{}
+	f := NewReleasesChangelogFormatter(testReleasesChangelog(), false)
+
+	err := f.Template(sb, `{{ range .Releases }}{{ .CurrentVersion }}:{{ range .ChangesByLevel "minor" }} {{ .Title | upper }}{{ end }}
+{{ end }}`)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0: SECOND\nv1.0.0:\n", sb.String())
+}
+
+func TestReleasesFormatter_Console_changelog(t *testing.T) {
+	sb := &strings.Builder{}
+	f := NewReleasesChangelogFormatter(testReleasesChangelog(), false)
+	require.NoError(t, f.Console(sb))
+
+	lines := strings.Split(sb.String(), "\n")
+	assert.Equal(t, "Release v1.1.0:", lines[0])
+	assert.Contains(t, lines[3], "second")
+	assert.Equal(t, "Release v1.0.0:", lines[5])
+	assert.Equal(t, "No change", lines[6])
+}
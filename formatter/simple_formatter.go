@@ -1,32 +1,69 @@
 package formatter
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"text/template"
+	"io"
+
+	"gopkg.in/yaml.v2"
 )
 
+var errNoRelease = errors.New("the value has no release")
+
+// SimpleFormatter writes a single value. The value is encoded as it is without key,
+// in an object {key: value} otherwise
 type SimpleFormatter struct {
 	Key   string
 	Value interface{}
+	// Release is the release of the value, given to the templates
+	Release *ReleaseDTO
+	templateOptions
 }
 
-func (f *SimpleFormatter) Template(text string) error {
-	tpl, err := template.New("Release").Parse(text)
-	if err != nil {
-		return err
+func (f *SimpleFormatter) Template(w io.Writer, text string) error {
+	if f.Release == nil {
+		return f.execute(w, text, "", ReleaseDTO{}, nil)
 	}
-	return tpl.Execute(os.Stdout, f.Value)
+	return f.execute(w, text, f.Release.NextVersion, *f.Release, []ReleaseDTO{*f.Release})
 }
 
-func (f *SimpleFormatter) Json() {
-	fmt.Printf("{\"%s\":\"%s\"}\n", f.Key, f.Value)
+func (f *SimpleFormatter) Json(w io.Writer) error {
+	if f.Key == "" {
+		return writeJSON(w, f.Value)
+	}
+	return writeJSON(w, map[string]interface{}{f.Key: f.Value})
 }
 
-func (f *SimpleFormatter) Yaml() {
-	fmt.Printf("%s: \"%s\"\n", f.Key, f.Value)
+func (f *SimpleFormatter) Yaml(w io.Writer) error {
+	if f.Key == "" {
+		return writeYAML(w, f.Value)
+	}
+	return writeYAML(w, yaml.MapSlice{{Key: f.Key, Value: f.Value}})
+}
+
+func (f *SimpleFormatter) GithubActions(w io.Writer) error {
+	if f.Release == nil {
+		return errNoRelease
+	}
+	return writeGithubActions(w, f.Release.NextVersion, f.Release, f.templateOptions)
+}
+
+func (f *SimpleFormatter) Dotenv(w io.Writer) error {
+	if f.Release == nil {
+		return errNoRelease
+	}
+	return writeDotenv(w, f.Release)
+}
+
+func (f *SimpleFormatter) Env(w io.Writer) error {
+	if f.Release == nil {
+		return errNoRelease
+	}
+	return writeEnv(w, f.Release)
 }
 
-func (f *SimpleFormatter) Console() {
-	fmt.Println(f.Value)
+// Console prints the value, a fmt.Stringer prints its string
+func (f *SimpleFormatter) Console(w io.Writer) error {
+	_, err := fmt.Fprintln(w, f.Value)
+	return err
 }
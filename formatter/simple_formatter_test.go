@@ -0,0 +1,63 @@
+package formatter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSimpleFormatter_escaping(t *testing.T) {
+	f := &SimpleFormatter{Key: "title", Value: `say "hello" \ bye`}
+
+	sb := &strings.Builder{}
+	require.NoError(t, f.Json(sb))
+	var j map[string]string
+	require.NoError(t, json.Unmarshal([]byte(sb.String()), &j))
+	assert.Equal(t, `say "hello" \ bye`, j["title"])
+
+	sb.Reset()
+	require.NoError(t, f.Yaml(sb))
+	var y map[string]string
+	require.NoError(t, yaml.Unmarshal([]byte(sb.String()), &y))
+	assert.Equal(t, `say "hello" \ bye`, y["title"])
+}
+
+func TestSimpleFormatter_nextVersion(t *testing.T) {
+	r := &ReleaseDTO{
+		CurrentVersion: "v1.2.0",
+		NextVersion:    "v1.3.0-rc.1",
+		Ref:            "1c23cc3",
+		Changelog: []ReleaseItemDTO{
+			{Title: "fix", Level: model.ChangeLevelPatch},
+			{Title: "feature", Level: model.ChangeLevelMinor},
+		},
+	}
+	f := &SimpleFormatter{Value: MapNextVersion(r), Release: r}
+
+	sb := &strings.Builder{}
+	require.NoError(t, f.Json(sb))
+	assert.JSONEq(t, `{"next_version":"v1.3.0-rc.1","current_version":"v1.2.0","bump_level":"MINOR","prerelease":"rc.1","ref":"1c23cc3"}`, sb.String())
+
+	sb.Reset()
+	require.NoError(t, f.Yaml(sb))
+	assert.Equal(t, `next_version: v1.3.0-rc.1
+current_version: v1.2.0
+bump_level: MINOR
+prerelease: rc.1
+ref: 1c23cc3
+`, sb.String())
+
+	sb.Reset()
+	require.NoError(t, f.Console(sb))
+	assert.Equal(t, "v1.3.0-rc.1\n", sb.String())
+}
+
+func TestMapNextVersion_dateVersion(t *testing.T) {
+	v := MapNextVersion(&ReleaseDTO{NextVersion: "2006-01-02-150405"})
+	assert.Equal(t, NextVersionDTO{NextVersion: "2006-01-02-150405"}, v)
+}
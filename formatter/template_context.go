@@ -0,0 +1,105 @@
+package formatter
+
+import (
+	"io"
+	"sort"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+	"github.com/tauffredou/nextver/model"
+	"github.com/willf/pad"
+)
+
+// TemplateContext is the data of the templates, the same for every command.
+// The fields of the release are the ones of the release of the command:
+// the next release, or the release given by --release. They are empty when the command reads no single release
+type TemplateContext struct {
+	ReleaseDTO
+	// Version is the version of the release: the name given by --release, the next version otherwise
+	Version string
+	// Releases are the releases read by the command
+	Releases []ReleaseDTO
+	// Config is the configuration file of the repository, empty without file
+	Config *model.Config
+}
+
+// templateOptions are shared by the formatters rendering templates
+type templateOptions struct {
+	links  model.Links
+	config *model.Config
+}
+
+// SetLinks gives the web pages of the repository, linked in the markdown output and by commitURL in the templates
+func (o *templateOptions) SetLinks(links model.Links) {
+	o.links = links
+}
+
+// SetConfig gives the configuration of the repository to the templates
+func (o *templateOptions) SetConfig(config *model.Config) {
+	o.config = config
+}
+
+// execute renders the template with the functions of FuncMap, version is the version of the release
+func (o *templateOptions) execute(w io.Writer, text string, version string, release ReleaseDTO, releases []ReleaseDTO) error {
+	tpl, err := template.New("Release").
+		Funcs(FuncMap(o.links)).
+		Parse(text)
+	if err != nil {
+		return err
+	}
+
+	config := o.config
+	if config == nil {
+		config = &model.Config{}
+	}
+	if releases == nil {
+		releases = []ReleaseDTO{}
+	}
+	return tpl.Execute(w, &TemplateContext{ReleaseDTO: release, Version: version, Releases: releases, Config: config})
+}
+
+// FuncMap is the set of functions of the templates: the sprig functions and the nextver helpers
+func FuncMap(links model.Links) template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	funcs["padRight"] = pad.Right
+	funcs["groupByKind"] = groupByKind
+	funcs["groupByScope"] = groupByScope
+	funcs["commitURL"] = links.CommitURL
+	funcs["shortSha"] = shortRef
+	funcs["semverParts"] = model.FindSemver
+	funcs["contributors"] = contributors
+	return funcs
+}
+
+// groupByKind maps the kinds to their changes, the changes without kind are under an empty kind
+func groupByKind(changes []ReleaseItemDTO) map[string][]ReleaseItemDTO {
+	res := make(map[string][]ReleaseItemDTO)
+	for _, c := range changes {
+		res[c.Kind] = append(res[c.Kind], c)
+	}
+	return res
+}
+
+// groupByScope maps the scopes to their changes, the changes without scope are under an empty scope
+func groupByScope(changes []ReleaseItemDTO) map[string][]ReleaseItemDTO {
+	res := make(map[string][]ReleaseItemDTO)
+	for _, c := range changes {
+		res[c.Scope] = append(res[c.Scope], c)
+	}
+	return res
+}
+
+// contributors lists the authors of the changes once, sorted
+func contributors(changes []ReleaseItemDTO) []string {
+	seen := make(map[string]bool)
+	res := []string{}
+	for _, c := range changes {
+		if c.Author == "" || seen[c.Author] {
+			continue
+		}
+		seen[c.Author] = true
+		res = append(res, c.Author)
+	}
+	sort.Strings(res)
+	return res
+}
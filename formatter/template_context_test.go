@@ -0,0 +1,82 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+)
+
+func executeTemplate(t *testing.T, f Formatter, text string) string {
+	sb := &strings.Builder{}
+	require.NoError(t, f.Template(sb, text))
+	return sb.String()
+}
+
+func TestTemplateContext_sameForEveryFormatter(t *testing.T) {
+	r := templateRelease()
+	config := &model.Config{Pattern: "vSEMVER"}
+	text := `{{ .Project }} {{ .NextVersion }} {{ len .Releases }} {{ .Config.Pattern }}`
+
+	changelog := NewChangelogFormatter(r, false)
+	changelog.SetConfig(config)
+	assert.Equal(t, "nextver v1.0.0 1 vSEMVER", executeTemplate(t, changelog, text))
+
+	releases := NewReleasesFormatter([]ReleaseDTO{{Project: "nextver", CurrentVersion: "v0.1.0"}, {Project: "nextver"}})
+	releases.SetConfig(config)
+	assert.Equal(t, "nextver  2 vSEMVER", executeTemplate(t, releases, text))
+	releases.SetNextRelease(r)
+	assert.Equal(t, "nextver v1.0.0 2 vSEMVER", executeTemplate(t, releases, text))
+
+	simple := &SimpleFormatter{Key: "next-version", Value: "v1.0.0", Release: r}
+	simple.SetConfig(config)
+	assert.Equal(t, "nextver v1.0.0 1 vSEMVER", executeTemplate(t, simple, text))
+}
+
+func TestTemplateContext_version(t *testing.T) {
+	text := `{{ .Version }}`
+
+	changelog := NewChangelogFormatter(templateRelease(), false)
+	assert.Equal(t, "v1.0.0", executeTemplate(t, changelog, text))
+	changelog.SetRelease("v0.9.0")
+	assert.Equal(t, "v0.9.0", executeTemplate(t, changelog, text))
+
+	releases := NewReleasesFormatter([]ReleaseDTO{{Project: "nextver", CurrentVersion: "v0.1.0"}})
+	assert.Equal(t, "", executeTemplate(t, releases, text))
+	releases.SetNextRelease(templateRelease())
+	assert.Equal(t, "v1.0.0", executeTemplate(t, releases, text))
+}
+
+func TestTemplateContext_emptyConfig(t *testing.T) {
+	f := NewChangelogFormatter(templateRelease(), false)
+	assert.Equal(t, "[]", executeTemplate(t, f, `[{{ .Config.Pattern }}]`))
+}
+
+func TestFuncMap(t *testing.T) {
+	r := templateRelease()
+	r.Changelog = append(r.Changelog, ReleaseItemDTO{ID: "784e8b02254bae917a276691fd45b8256fb491e8", Kind: "fix", Scope: "api", Title: "check the body", Author: "tauf"})
+	f := NewChangelogFormatter(r, false)
+	f.SetLinks(model.Links{Commit: "https://github.com/owner/repo/commit/{ref}"})
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{`{{ range $kind, $changes := groupByKind .Changelog }}{{ $kind }}={{ len $changes }} {{ end }}`, "feat=1 fix=2 "},
+		{`{{ range $scope, $changes := groupByScope .Changelog }}[{{ $scope }}]={{ len $changes }} {{ end }}`, "[]=1 [api]=2 "},
+		{`{{ range .Changelog }}{{ with .ID }}{{ shortSha . }} {{ commitURL . }}{{ end }}{{ end }}`, "784e8b0 https://github.com/owner/repo/commit/784e8b02254bae917a276691fd45b8256fb491e8"},
+		{`{{ with semverParts .NextVersion }}{{ .Major }}.{{ .Minor }}.{{ .Patch }}{{ end }}`, "1.0.0"},
+		{`{{ contributors .Changelog | join ", " }}`, "bob, tauf"},
+		{`{{ padRight "a" 3 "." }}`, "a.."},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, executeTemplate(t, f, test.text), test.text)
+	}
+}
+
+func TestFuncMap_semverPartsError(t *testing.T) {
+	f := NewChangelogFormatter(&ReleaseDTO{NextVersion: "2006-01-02-150405"}, false)
+	assert.Error(t, f.Template(&strings.Builder{}, `{{ semverParts .NextVersion }}`))
+}
@@ -0,0 +1,120 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BuiltinTemplatePrefix selects a built-in template instead of a template file
+const BuiltinTemplatePrefix = "builtin:"
+
+// BuiltinTemplate is a template shipped with the binary, rendered with the changelog of a release
+type BuiltinTemplate struct {
+	Name        string
+	Description string
+	Text        string
+}
+
+// BuiltinTemplates are listed by name
+var BuiltinTemplates = []BuiltinTemplate{
+	{
+		Name:        "github-release",
+		Description: "body of a Github release, the changes with their author and pull request",
+		Text:        githubReleaseTemplate,
+	},
+	{
+		Name:        "markdown",
+		Description: "markdown changelog, the changes grouped by level",
+		Text:        markdownTemplate,
+	},
+	{
+		Name:        "release-notes",
+		Description: "plain text release notes",
+		Text:        releaseNotesTemplate,
+	},
+	{
+		Name:        "slack",
+		Description: "Slack message (mrkdwn)",
+		Text:        slackTemplate,
+	},
+}
+
+// LookupBuiltinTemplate finds a built-in template by name, with or without the builtin: prefix
+func LookupBuiltinTemplate(name string) (BuiltinTemplate, error) {
+	name = strings.TrimPrefix(name, BuiltinTemplatePrefix)
+	for _, t := range BuiltinTemplates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	names := make([]string, len(BuiltinTemplates))
+	for i, t := range BuiltinTemplates {
+		names[i] = t.Name
+	}
+	return BuiltinTemplate{}, fmt.Errorf("unknown template %s, the built-in templates are %s", name, strings.Join(names, ", "))
+}
+
+const markdownTemplate = `## {{ .Version }}
+{{ if .HasChanges "MAJOR" }}
+### Breaking Changes
+{{ range .ChangesByLevel "MAJOR" }}
+- {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .Title }}{{ with .PullRequest }} (#{{ .Number }}){{ end }}
+{{- end }}
+{{ end -}}
+{{ if .HasChanges "MINOR" }}
+### Features
+{{ range .ChangesByLevel "MINOR" }}
+- {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .Title }}{{ with .PullRequest }} (#{{ .Number }}){{ end }}
+{{- end }}
+{{ end -}}
+{{ if .HasChanges "PATCH" }}
+### Fixes
+{{ range .ChangesByLevel "PATCH" }}
+- {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .Title }}{{ with .PullRequest }} (#{{ .Number }}){{ end }}
+{{- end }}
+{{ end -}}
+`
+
+const slackTemplate = `*{{ .Project }} {{ .Version }}*
+{{ if .HasChanges "MAJOR" }}
+:warning: *Breaking changes*
+{{ range .ChangesByLevel "MAJOR" }}• {{ .Title }}
+{{ end }}{{ end -}}
+{{ if .HasChanges "MINOR" }}
+:sparkles: *Features*
+{{ range .ChangesByLevel "MINOR" }}• {{ .Title }}
+{{ end }}{{ end -}}
+{{ if .HasChanges "PATCH" }}
+:bug: *Fixes*
+{{ range .ChangesByLevel "PATCH" }}• {{ .Title }}
+{{ end }}{{ end -}}
+`
+
+const releaseNotesTemplate = `{{ .Project }} {{ .Version }}
+{{ if .CurrentVersion }}Changes since {{ .CurrentVersion }}
+{{ end }}
+{{- if not .Changelog }}
+No change.
+{{ end -}}
+{{ if .HasChanges "MAJOR" }}
+Breaking changes:
+{{ range .ChangesByLevel "MAJOR" }}  * {{ .Title }}
+{{ end }}{{ end -}}
+{{ if .HasChanges "MINOR" }}
+New features:
+{{ range .ChangesByLevel "MINOR" }}  * {{ .Title }}
+{{ end }}{{ end -}}
+{{ if .HasChanges "PATCH" }}
+Bug fixes:
+{{ range .ChangesByLevel "PATCH" }}  * {{ .Title }}
+{{ end }}{{ end -}}
+`
+
+const githubReleaseTemplate = `## What's Changed
+{{ range .Changelog }}
+* {{ if .Kind }}{{ .Kind }}{{ if .Scope }}({{ .Scope }}){{ end }}: {{ end }}{{ .Title }} by @{{ .Author }}{{ with .PullRequest }} in {{ .URL }}{{ end }}
+{{- end }}
+{{ if .CurrentVersion }}
+**Full Changelog**: {{ .CurrentVersion }}...{{ .Version }}
+{{ end -}}
+`
@@ -0,0 +1,112 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+)
+
+func templateRelease() *ReleaseDTO {
+	return &ReleaseDTO{
+		Project:        "nextver",
+		CurrentVersion: "v0.1.0",
+		NextVersion:    "v1.0.0",
+		Changelog: []ReleaseItemDTO{
+			{Kind: "feat", Scope: "api", Title: "rework the api", Level: model.ChangeLevelMajor, Author: "tauf"},
+			{Kind: "fix", Title: "check the token", Level: model.ChangeLevelPatch, Author: "bob",
+				PullRequest: &PullRequestDTO{Number: 12, URL: "https://github.com/tauffredou/nextver/pull/12"}},
+		},
+	}
+}
+
+func renderTemplate(t *testing.T, name string, r *ReleaseDTO) string {
+	tpl, err := LookupBuiltinTemplate(name)
+	require.NoError(t, err)
+	sb := &strings.Builder{}
+	require.NoError(t, NewChangelogFormatter(r, false).Template(sb, tpl.Text))
+	return sb.String()
+}
+
+func TestLookupBuiltinTemplate(t *testing.T) {
+	tpl, err := LookupBuiltinTemplate("builtin:slack")
+	assert.NoError(t, err)
+	assert.Equal(t, "slack", tpl.Name)
+
+	tpl, err = LookupBuiltinTemplate("markdown")
+	assert.NoError(t, err)
+	assert.Equal(t, "markdown", tpl.Name)
+
+	_, err = LookupBuiltinTemplate("builtin:unknown")
+	assert.EqualError(t, err, "unknown template unknown, the built-in templates are github-release, markdown, release-notes, slack")
+}
+
+func TestBuiltinTemplates_render(t *testing.T) {
+	for _, tpl := range BuiltinTemplates {
+		for _, r := range []*ReleaseDTO{templateRelease(), {Project: "nextver", NextVersion: "v0.1.0", Changelog: []ReleaseItemDTO{}}} {
+			assert.NotEmpty(t, renderTemplate(t, tpl.Name, r), tpl.Name)
+		}
+	}
+}
+
+func TestBuiltinTemplates_release(t *testing.T) {
+	tpl, err := LookupBuiltinTemplate("markdown")
+	require.NoError(t, err)
+	f := NewChangelogFormatter(templateRelease(), false)
+	f.SetRelease("v0.9.0")
+	sb := &strings.Builder{}
+	require.NoError(t, f.Template(sb, tpl.Text))
+	assert.True(t, strings.HasPrefix(sb.String(), "## v0.9.0\n"), sb.String())
+}
+
+func TestBuiltinTemplates_markdown(t *testing.T) {
+	expected := `## v1.0.0
+
+### Breaking Changes
+
+- **api:** rework the api
+
+### Fixes
+
+- check the token (#12)
+`
+	assert.Equal(t, expected, renderTemplate(t, "markdown", templateRelease()))
+}
+
+func TestBuiltinTemplates_slack(t *testing.T) {
+	expected := `*nextver v1.0.0*
+
+:warning: *Breaking changes*
+• rework the api
+
+:bug: *Fixes*
+• check the token
+`
+	assert.Equal(t, expected, renderTemplate(t, "slack", templateRelease()))
+}
+
+func TestBuiltinTemplates_releaseNotes(t *testing.T) {
+	expected := `nextver v1.0.0
+Changes since v0.1.0
+
+Breaking changes:
+  * rework the api
+
+Bug fixes:
+  * check the token
+`
+	assert.Equal(t, expected, renderTemplate(t, "release-notes", templateRelease()))
+}
+
+func TestBuiltinTemplates_githubRelease(t *testing.T) {
+	expected := `## What's Changed
+
+* feat(api): rework the api by @tauf
+* fix: check the token by @bob in https://github.com/tauffredou/nextver/pull/12
+
+**Full Changelog**: v0.1.0...v1.0.0
+`
+	assert.Equal(t, expected, renderTemplate(t, "github-release", templateRelease()))
+}
@@ -1,39 +1,88 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	log "github.com/sirupsen/logrus"
 	"github.com/tauffredou/nextver/formatter"
+	"github.com/tauffredou/nextver/model"
 	"github.com/tauffredou/nextver/provider"
+	"github.com/tauffredou/nextver/sorter"
 	"gopkg.in/alecthomas/kingpin.v2"
+	"io"
 	"io/ioutil"
 	"os"
+	"os/signal"
 	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
-	tokenFlag = kingpin.Flag("github-token", "Github token. Can be read form hub config file").Envar("GITHUB_TOKEN").String()
+	tokenFlag          = kingpin.Flag("github-token", "Github token. Can be read form hub config file").Envar("GITHUB_TOKEN").String()
+	githubURLFlag      = kingpin.Flag("github-url", "Github Enterprise Server url").Envar("GITHUB_API_URL").String()
+	githubHostsFlag    = kingpin.Flag("github-host", "Custom host name served by the github provider. Can be repeated").Strings()
+	gitlabTokenFlag    = kingpin.Flag("gitlab-token", "Gitlab token. Optional for public projects").Envar("GITLAB_TOKEN").String()
+	giteaTokenFlag     = kingpin.Flag("gitea-token", "Gitea/Forgejo token. Optional for public repositories").Envar("GITEA_TOKEN").String()
+	bitbucketTokenFlag = kingpin.Flag("bitbucket-token", "Bitbucket access token or username:app-password").Envar("BITBUCKET_TOKEN").String()
+	providerFlag       = kingpin.Flag("provider", "Provider ("+strings.Join(provider.ProviderNames(), ", ")+"). Guessed from the host of the public forges if empty").String()
+	apiURLFlag         = kingpin.Flag("api-url", "API endpoint of the provider").String()
 
-	repo     = kingpin.Flag("repo", "Repository").Default(".").Short('r').String()
-	pattern  = kingpin.Flag("pattern", "Versionning pattern. Read from .nextver/config.yml by default").Short('p').String()
-	output   = kingpin.Flag("output", "Output format (console, json, yaml, template)").Short('o').Default("console").String()
-	branch   = kingpin.Flag("branch", "Target branch (default branch if empty)").Short('b').String()
-	logLevel = kingpin.Flag("log-level", "Log level").Default("info").String()
+	repo         = kingpin.Flag("repo", "Repository").Default(".").Short('r').String()
+	pattern      = kingpin.Flag("pattern", "Versionning pattern. Read from .nextver/config.yml by default").Short('p').String()
+	output       = kingpin.Flag("output", "Output format (console, json, yaml, markdown, template, github-actions, dotenv, env, html)").Short('o').Default("console").String()
+	branch       = kingpin.Flag("branch", "Target branch (default branch, or HEAD of a git repository, if empty)").Short('b').String()
+	logLevel     = kingpin.Flag("log-level", "Log level").Default("info").String()
+	timeout      = kingpin.Flag("timeout", "Maximum duration of the command, unlimited when 0").Default("0").Duration()
+	retries      = kingpin.Flag("retries", "Retries of the failed github queries").Default(strconv.Itoa(provider.DefaultRetryConfig.MaxRetries)).Int()
+	retryMaxWait = kingpin.Flag("retry-max-wait", "Longest wait for a github rate limit reset").Default(provider.DefaultRetryConfig.MaxWait.String()).Duration()
+	cacheDir     = kingpin.Flag("cache-dir", "Directory of the API response cache, disabled when empty").Envar("NEXTVER_CACHE_DIR").String()
+	cacheTTL     = kingpin.Flag("cache-ttl", "Duration before a cached response is checked again").Default("5m").Duration()
 
 	color        = kingpin.Flag("color", "Colorize output").Default("true").Bool()
-	templateFile = kingpin.Flag("template", "Template file").String()
+	templateFile = kingpin.Flag("template", "Template file, or builtin:<name> for a built-in template").String()
+	htmlFragment = kingpin.Flag("html-fragment", "Render the html output as a fragment to insert in a page").Bool()
+	outputFile   = kingpin.Flag("output-file", "Write the output in this file instead of stdout, the file is replaced at once").String()
 
 	//get
 	getCommand       = kingpin.Command("get", "")
 	_                = getCommand.Command("releases", "List releases")
 	changelogCommand = getCommand.Command("changelog", "Get changelog")
 	release          = changelogCommand.Flag("release", "Changelog for a specific release").Default("").String()
+	allReleases      = changelogCommand.Flag("all", "Changelogs of all the releases").Bool()
+	fromRelease      = changelogCommand.Flag("from", "Changelogs of the releases since this one, included").String()
+	toRelease        = changelogCommand.Flag("to", "Changelogs of the releases until this one, included").String()
+	pullRequests     = getCommand.Flag("pull-requests", "Build the changelog from the merged pull requests (github only)").Bool()
 	_                = getCommand.Command("next-version", "Get next version")
 
+	//cache
+	cacheCommand = kingpin.Command("cache", "Inspect the API response cache")
+	_            = cacheCommand.Command("list", "List the cached responses")
+	_            = cacheCommand.Command("clear", "Remove the cached responses")
+
+	//templates
+	templatesCommand = kingpin.Command("templates", "Inspect the built-in templates")
+	_                = templatesCommand.Command("list", "List the built-in templates")
+	showTemplate     = templatesCommand.Command("show", "Print a built-in template").Arg("name", "Template name").Required().String()
+
+	//update
+	updateCommand          = kingpin.Command("update", "")
+	updateChangelogCommand = updateCommand.Command("changelog", "Add the unreleased changes and the new releases to the changelog file (Keep a Changelog format)")
+	changelogFile          = updateChangelogCommand.Flag("file", "Changelog file").Default("CHANGELOG.md").String()
+	regenerate             = updateChangelogCommand.Flag("regenerate", "Write the whole file from all the releases").Bool()
+
 	//create
 	//createCommand = kingpin.Command("create", "")
 
 	//_ = createCommand.Command("release", "Create release")
 
+	errCacheDisabled = errors.New("the cache is disabled, set --cache-dir")
+
 	defaultHubConfig = path.Join(MustString(os.UserHomeDir()), ".config", "hub")
 )
 
@@ -72,80 +121,505 @@ func main() {
 	log.SetOutput(os.Stderr)
 	var f formatter.Formatter
 
-	pf := provider.ProviderFactory{
-		Pattern:     *pattern,
-		TokenReader: githubToken,
+	ctx, cancel := newContext(*timeout)
+	defer cancel()
+
+	var cache *provider.Cache
+	if *cacheDir != "" {
+		cache = provider.NewCache(*cacheDir, *cacheTTL)
 	}
 
-	prov, err := pf.CreateProvider(*repo)
-	if err != nil {
-		log.Fatal(err)
+	switch parse {
+	case "cache list":
+		checkErr(ctx, listCache(cache))
+		return
+	case "cache clear":
+		checkErr(ctx, clearCache(cache))
+		return
+	case "templates list":
+		checkErr(ctx, listTemplates())
+		return
+	case "templates show":
+		checkErr(ctx, printTemplate(*showTemplate))
+		return
+	}
+
+	pf := provider.ProviderFactory{
+		Pattern:              *pattern,
+		Branch:               *branch,
+		TokenReader:          githubToken,
+		GitlabTokenReader:    func() string { return *gitlabTokenFlag },
+		GiteaTokenReader:     func() string { return *giteaTokenFlag },
+		BitbucketTokenReader: func() string { return *bitbucketTokenFlag },
+		Provider:             *providerFlag,
+		ApiURL:               *apiURLFlag,
+		GithubURL:            *githubURLFlag,
+		GithubHosts:          *githubHostsFlag,
+		Retry:                retryConfig(),
+		Cache:                cache,
+		PullRequests:         *pullRequests,
 	}
 
+	prov, err := pf.CreateProvider(ctx, *repo)
+	checkErr(ctx, err)
+
 	switch parse {
 	case "get next-version":
-		f = getNextVersion(prov)
+		f, err = getNextVersion(ctx, prov)
 		// Releases
 	case "get releases":
-		f = getReleases(prov)
+		f, err = getReleases(ctx, prov)
 	case "create release":
 		log.Warn("not implemented yet")
 	case "get changelog":
-		f = getChangelog(prov)
+		f, err = getChangelog(ctx, prov)
+	case "update changelog":
+		err = updateChangelogFile(ctx, prov)
 	}
+	checkErr(ctx, err)
 
+	if f != nil && *output == "template" {
+		checkErr(ctx, setTemplateContext(ctx, prov, f))
+	}
 	if f != nil {
+		checkErr(ctx, writeOutput(f))
+	}
+}
+
+// writeOutput renders the result on stdout, or in the output file replaced at once.
+// The github-actions output is appended to $GITHUB_OUTPUT when it is set, the file holds the outputs of the other steps
+func writeOutput(f formatter.Formatter) error {
+	if githubOutput := os.Getenv("GITHUB_OUTPUT"); *output == "github-actions" && *outputFile == "" && githubOutput != "" {
+		return appendOutput(f, githubOutput)
+	}
+	if *outputFile == "" {
+		return render(f, os.Stdout)
+	}
+
+	var buf bytes.Buffer
+	if err := render(f, &buf); err != nil {
+		return err
+	}
+	return writeFileAtomic(*outputFile, buf.Bytes())
+}
+
+func appendOutput(f formatter.Formatter, name string) error {
+	var buf bytes.Buffer
+	if err := render(f, &buf); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func render(f formatter.Formatter, w io.Writer) error {
+	switch *output {
+	case "console":
+		return f.Console(w)
+	case "json":
+		return f.Json(w)
+	case "yaml":
+		return f.Yaml(w)
+	case "markdown":
+		m, ok := f.(formatter.MarkdownFormatter)
+		if !ok {
+			return errors.New("markdown output is not available for this command")
+		}
+		return m.Markdown(w)
+	case "html":
+		h, ok := f.(formatter.HTMLFormatter)
+		if !ok {
+			return errors.New("html output is not available for this command")
+		}
+		return h.HTML(w, *htmlFragment)
+	case "github-actions", "dotenv", "env":
+		c, ok := f.(formatter.CIFormatter)
+		if !ok {
+			return fmt.Errorf("%s output is not available for this command", *output)
+		}
 		switch *output {
-		case "console":
-			f.Console()
-		case "json":
-			f.Json()
-		case "yaml":
-			f.Yaml()
-		case "template":
-			if *templateFile == "" {
-				log.Fatal("template parameter is required")
-			}
-			text, err := ioutil.ReadFile(*templateFile)
-			if err != nil {
-				log.Error(err)
-			}
-			err = f.Template(string(text))
+		case "dotenv":
+			return c.Dotenv(w)
+		case "env":
+			return c.Env(w)
+		}
+		return c.GithubActions(w)
+	case "template":
+		if *templateFile == "" {
+			return errors.New("template parameter is required")
+		}
+		text, err := readTemplate(*templateFile)
+		if err != nil {
+			return err
+		}
+		return f.Template(w, text)
+	}
+	return fmt.Errorf("unknown output %s", *output)
+}
+
+// readTemplate reads a template file or a built-in template
+func readTemplate(name string) (string, error) {
+	if strings.HasPrefix(name, formatter.BuiltinTemplatePrefix) {
+		t, err := formatter.LookupBuiltinTemplate(name)
+		return t.Text, err
+	}
+	text, err := ioutil.ReadFile(name)
+	return string(text), err
+}
+
+// colorize tells if the console output is colorized, never in a file
+func colorize() bool {
+	return *color && *outputFile == ""
+}
+
+func getNextVersion(ctx context.Context, prov provider.Provider) (formatter.Formatter, error) {
+	r, err := prov.GetRelease(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	dto, err := formatter.MapRelease(r)
+	if err != nil {
+		return nil, err
+	}
+	f := &formatter.SimpleFormatter{Value: formatter.MapNextVersion(&dto), Release: &dto}
+	f.SetLinks(providerLinks(prov))
+	return f, nil
+}
+
+func getReleases(ctx context.Context, prov provider.Provider) (formatter.Formatter, error) {
+	r, err := prov.GetReleases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m, err := formatter.MapReleases(r)
+	if err != nil {
+		return nil, err
+	}
+	f := formatter.NewReleasesFormatter(m)
+	f.SetLinks(providerLinks(prov))
+	return f, nil
+}
+
+func getChangelog(ctx context.Context, prov provider.Provider) (formatter.Formatter, error) {
+	if *allReleases || *fromRelease != "" || *toRelease != "" {
+		if *release != "" {
+			return nil, errors.New("--release cannot be combined with --all, --from or --to")
+		}
+		releases, err := releaseChangelogs(ctx, prov, *fromRelease, *toRelease)
+		if err != nil {
+			return nil, err
+		}
+		f := formatter.NewReleasesChangelogFormatter(releases, colorize())
+		f.SetLinks(providerLinks(prov))
+		return f, nil
+	}
+
+	r, err := prov.GetRelease(ctx, *release)
+	if err != nil {
+		return nil, err
+	}
+	dto, err := formatter.MapRelease(r)
+	if err != nil {
+		return nil, err
+	}
+	f := formatter.NewChangelogFormatter(&dto, colorize())
+	f.SetLinks(providerLinks(prov))
+	f.SetRelease(*release)
+	return f, nil
+}
+
+// releaseChangelogs reads the changes of the releases between from and to, included, the latest first.
+// All the releases are read when both are empty
+func releaseChangelogs(ctx context.Context, prov provider.Provider, from string, to string) ([]formatter.ReleaseDTO, error) {
+	releases, err := prov.GetReleases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sorter.BySemver(releases))
+
+	first, last := 0, len(releases)-1
+	if to != "" {
+		if first = indexOfRelease(releases, to); first == -1 {
+			return nil, &provider.NotFoundError{Resource: "release " + to}
+		}
+	}
+	if from != "" {
+		if last = indexOfRelease(releases, from); last == -1 {
+			return nil, &provider.NotFoundError{Resource: "release " + from}
+		}
+	}
+	if first > last {
+		return nil, fmt.Errorf("release %s is older than release %s", to, from)
+	}
+
+	res := make([]formatter.ReleaseDTO, 0, last-first+1)
+	for _, r := range releases[first : last+1] {
+		release, err := prov.GetRelease(ctx, r.CurrentVersion)
+		if err != nil {
+			return nil, err
+		}
+		dto, err := formatter.MapRelease(release)
+		if err != nil {
+			return nil, err
+		}
+		// a published release is its own version, the version computed from its tag is another release
+		dto.CurrentVersion = r.CurrentVersion
+		dto.NextVersion = r.CurrentVersion
+		dto.Ref = r.Ref
+		if dto.Project == "" {
+			dto.Project = r.Project
+		}
+		res = append(res, dto)
+	}
+	return res, nil
+}
+
+func indexOfRelease(releases []model.Release, name string) int {
+	for i := range releases {
+		if releases[i].CurrentVersion == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateChangelogFile writes the changes not released yet in the Unreleased section of the changelog file,
+// and the sections of the releases tagged since the previous update.
+// With --regenerate, the file is written again from all the releases
+func updateChangelogFile(ctx context.Context, prov provider.Provider) error {
+	links := providerLinks(prov)
+	c := formatter.NewKeepAChangelog()
+	if !*regenerate {
+		text, err := ioutil.ReadFile(*changelogFile)
+		switch {
+		case err == nil:
+			c = formatter.ParseKeepAChangelog(string(text))
+		case !os.IsNotExist(err):
+			return err
+		}
+	}
+
+	releases, err := newReleaseChangelogs(ctx, prov, c)
+	if err != nil {
+		return err
+	}
+	for i := len(releases) - 1; i >= 0; i-- {
+		c.SetRelease(releases[i].CurrentVersion, formatter.ReleaseDate(&releases[i]), &releases[i], links)
+	}
+
+	r, err := prov.GetRelease(ctx, "")
+	if err != nil {
+		return err
+	}
+	dto, err := formatter.MapRelease(r)
+	if err != nil {
+		return err
+	}
+	if len(dto.Changelog) == 0 {
+		log.Info("No change since last release")
+	}
+	c.SetUnreleased(&dto, links)
+
+	return writeFileAtomic(*changelogFile, []byte(c.String()))
+}
+
+// newReleaseChangelogs reads the changes of the releases newer than the latest release of the changelog, the latest first.
+// The older releases missing from the changelog are left out, they were removed or never written on purpose
+func newReleaseChangelogs(ctx context.Context, prov provider.Provider, c *formatter.KeepAChangelog) ([]formatter.ReleaseDTO, error) {
+	releases, err := prov.GetReleases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sorter.BySemver(releases))
+
+	n := 0
+	for n < len(releases) && !c.HasRelease(releases[n].CurrentVersion) {
+		n++
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return releaseChangelogs(ctx, prov, releases[n-1].CurrentVersion, releases[0].CurrentVersion)
+}
+
+// writeFileAtomic replaces the file with a renamed temporary file, a reader never sees a partial file
+func writeFileAtomic(name string, data []byte) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(name); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// setTemplateContext gives the configuration and the next release to the templates,
+// they are only read for the template output
+func setTemplateContext(ctx context.Context, prov provider.Provider, f formatter.Formatter) error {
+	if c, ok := f.(interface{ SetConfig(*model.Config) }); ok {
+		if r, ok := prov.(provider.ConfigReader); ok {
+			config, err := r.Config(ctx)
 			if err != nil {
-				log.Error(err)
+				log.WithError(err).Warn("Cannot read configuration")
 			}
+			c.SetConfig(config)
 		}
 	}
 
+	if f, ok := f.(*formatter.ReleasesFormatter); ok {
+		r, err := prov.GetRelease(ctx, "")
+		if err != nil {
+			return err
+		}
+		next, err := formatter.MapRelease(r)
+		if err != nil {
+			return err
+		}
+		f.SetNextRelease(&next)
+	}
+	return nil
 }
 
-func getNextVersion(prov provider.Provider) formatter.Formatter {
-	r, err := prov.GetRelease("")
-	if err != nil {
-		log.Fatal(err)
+// providerLinks returns the web pages of the repository when the provider knows them
+func providerLinks(prov provider.Provider) model.Links {
+	if l, ok := prov.(provider.Linker); ok {
+		return l.Links()
 	}
-	v, _ := r.NextVersion()
-	return &formatter.SimpleFormatter{Key: "next-version", Value: v}
+	return model.Links{}
 }
 
-func getReleases(prov provider.Provider) formatter.Formatter {
-	r, err := prov.GetReleases()
+func listCache(cache *provider.Cache) error {
+	if cache == nil {
+		return errCacheDisabled
+	}
+	entries, err := cache.Entries()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	m := formatter.MapReleases(r)
-	return formatter.NewReleasesFormatter(m)
+
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		state := "fresh"
+		if e.Expired {
+			state = "expired"
+		}
+		request := e.Request
+		if len(request) > 100 {
+			request = request[:100] + "..."
+		}
+		rows = append(rows, []string{e.StoredAt.Format(time.RFC3339), state, strconv.Itoa(e.Size), request})
+	}
+
+	t := formatter.NewTable(os.Stdout, "stored", "state", "size", "request")
+	for _, r := range rows {
+		_ = t.AnalyseRow(r...)
+	}
+	t.WriteHeaders()
+	for _, r := range rows {
+		t.WriteRow(r...)
+	}
+	return nil
+}
+
+func clearCache(cache *provider.Cache) error {
+	if cache == nil {
+		return errCacheDisabled
+	}
+	return cache.Clear()
 }
 
-func getChangelog(prov provider.Provider) formatter.Formatter {
-	r, err := prov.GetRelease(*release)
-	checkErr(err)
-	dto := formatter.MapRelease(r)
-	return formatter.NewChangelogFormatter(&dto, *color)
+func listTemplates() error {
+	t := formatter.NewTable(os.Stdout, "name", "description")
+	for _, b := range formatter.BuiltinTemplates {
+		_ = t.AnalyseRow(formatter.BuiltinTemplatePrefix+b.Name, b.Description)
+	}
+	t.WriteHeaders()
+	for _, b := range formatter.BuiltinTemplates {
+		t.WriteRow(formatter.BuiltinTemplatePrefix+b.Name, b.Description)
+	}
+	return nil
 }
 
-func checkErr(err error) {
+func printTemplate(name string) error {
+	t, err := formatter.LookupBuiltinTemplate(name)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	_, err = fmt.Print(t.Text)
+	return err
+}
+
+func retryConfig() *provider.RetryConfig {
+	c := provider.DefaultRetryConfig
+	c.MaxRetries = *retries
+	c.MaxWait = *retryMaxWait
+	return &c
+}
+
+// newContext is cancelled on SIGINT or when the timeout expires
+func newContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// checkErr exits on error, with a hint for the errors the user can fix
+func checkErr(ctx context.Context, err error) {
+	switch {
+	case err == nil:
+		return
+	case ctx.Err() == context.DeadlineExceeded:
+		log.WithError(err).Fatal("Timeout exceeded")
+	case ctx.Err() == context.Canceled:
+		log.WithError(err).Fatal("Interrupted")
+	case provider.IsAuthenticationError(err):
+		log.WithError(err).Fatal("Check the token of the provider")
+	case provider.IsRateLimitError(err):
+		log.WithError(err).Fatal("Try again later or use a token")
+	case provider.IsNotFound(err):
+		log.WithError(err).Fatal("Check the repository and the release")
+	case provider.IsConfigurationError(err):
+		log.WithError(err).Fatal("Check the flags and " + model.DefaultConfigFile)
 	}
+	log.Fatal(err)
 }
@@ -1 +1,173 @@
 package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/formatter"
+	"github.com/tauffredou/nextver/provider"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nextver")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "CHANGELOG.md")
+	require.NoError(t, ioutil.WriteFile(name, []byte("old"), 0600))
+	require.NoError(t, writeFileAtomic(name, []byte("new")))
+
+	content, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+
+	fi, err := os.Stat(name)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "the temporary file is removed")
+}
+
+func TestWriteFileAtomic_missingDirectory(t *testing.T) {
+	assert.Error(t, writeFileAtomic(filepath.Join(os.TempDir(), "nextver-missing", "out.json"), []byte("{}")))
+}
+
+func TestAppendOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nextver")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "github_output")
+	require.NoError(t, ioutil.WriteFile(name, []byte("other=step\n"), 0644))
+
+	previous := *output
+	*output = "console"
+	defer func() { *output = previous }()
+
+	f := &formatter.SimpleFormatter{Key: "version", Value: "v1.0.0"}
+	require.NoError(t, appendOutput(f, name))
+
+	content, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "other=step\nv1.0.0\n", string(content))
+}
+
+// tempChangelogFile points --file to a temporary file, the returned function restores it
+func tempChangelogFile(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "nextver")
+	require.NoError(t, err)
+
+	previous := *changelogFile
+	*changelogFile = filepath.Join(dir, "CHANGELOG.md")
+	return func() {
+		*changelogFile = previous
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func readChangelogFile(t *testing.T) string {
+	content, err := ioutil.ReadFile(*changelogFile)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestUpdateChangelogFile(t *testing.T) {
+	defer tempChangelogFile(t)()
+
+	date := time.Date(2019, 6, 25, 12, 35, 41, 0, time.UTC)
+	p := provider.NewMockProvider().
+		AddCommit("feat: first", "tauf", date).
+		Tag("v1.0.0").
+		AddCommit("fix: second", "tauf", date.Add(time.Hour))
+
+	require.NoError(t, updateChangelogFile(context.Background(), p))
+	content := readChangelogFile(t)
+	assert.Contains(t, content, "## [Unreleased]\n\n### Fixed\n\n- second (")
+	assert.NotContains(t, content, "v1.0.1", "the next version is unknown until it is tagged")
+	assert.Contains(t, content, "## [v1.0.0] - 2019-06-25\n\n### Added\n\n- first (")
+
+	// the release is tagged with another version than the computed one
+	p.AddCommit("feat: third", "tauf", date.Add(2*time.Hour)).Tag("v1.1.0")
+	require.NoError(t, updateChangelogFile(context.Background(), p))
+	content = readChangelogFile(t)
+	assert.Contains(t, content, "## [Unreleased]\n\n## [v1.1.0] - 2019-06-25\n\n### Added\n\n- third (")
+	assert.Equal(t, 1, strings.Count(content, "- second ("))
+	assert.True(t, strings.Index(content, "- second (") < strings.Index(content, "## [v1.0.0]"))
+}
+
+func TestUpdateChangelogFile_severalReleases(t *testing.T) {
+	defer tempChangelogFile(t)()
+
+	date := time.Date(2019, 6, 25, 12, 35, 41, 0, time.UTC)
+	p := provider.NewMockProvider().
+		AddCommit("feat: first", "tauf", date).
+		Tag("v1.0.0")
+	require.NoError(t, updateChangelogFile(context.Background(), p))
+
+	// two releases are tagged before the next update
+	p.AddCommit("fix: second", "tauf", date.Add(time.Hour)).
+		Tag("v1.0.1").
+		AddCommit("feat: third", "tauf", date.Add(2*time.Hour)).
+		Tag("v1.1.0").
+		AddCommit("fix: fourth", "tauf", date.Add(3*time.Hour))
+	require.NoError(t, updateChangelogFile(context.Background(), p))
+
+	content := readChangelogFile(t)
+	headings := []string{"## [Unreleased]\n\n### Fixed\n\n- fourth (", "## [v1.1.0]", "- third (", "## [v1.0.1]", "- second (", "## [v1.0.0]", "- first ("}
+	last := -1
+	for _, h := range headings {
+		i := strings.Index(content, h)
+		assert.True(t, i > last, "%q is after the previous section", h)
+		assert.Equal(t, 1, strings.Count(content, h), h)
+		last = i
+	}
+}
+
+func TestUpdateChangelogFile_olderReleasesKept(t *testing.T) {
+	defer tempChangelogFile(t)()
+	require.NoError(t, ioutil.WriteFile(*changelogFile, []byte("# Changelog\n\n## [v1.0.0] - 2019-06-25\n\n- written by hand\n"), 0644))
+
+	date := time.Date(2019, 6, 25, 12, 35, 41, 0, time.UTC)
+	p := provider.NewMockProvider().
+		AddCommit("feat: zero", "tauf", date).
+		Tag("v0.9.0").
+		AddCommit("feat: first", "tauf", date).
+		Tag("v1.0.0").
+		AddCommit("feat: second", "tauf", date.Add(time.Hour)).
+		Tag("v1.1.0")
+	require.NoError(t, updateChangelogFile(context.Background(), p))
+
+	content := readChangelogFile(t)
+	assert.Contains(t, content, "## [v1.1.0] - 2019-06-25\n\n### Added\n\n- second (")
+	assert.Contains(t, content, "## [v1.0.0] - 2019-06-25\n\n- written by hand\n")
+	assert.NotContains(t, content, "v0.9.0", "the releases older than the changelog are not added")
+}
+
+func TestReleaseChangelogs(t *testing.T) {
+	date := time.Date(2019, 6, 25, 12, 35, 41, 0, time.UTC)
+	p := provider.NewMockProvider().
+		AddCommit("feat: first", "tauf", date).
+		Tag("v1.0.0").
+		AddCommit("feat: second", "tauf", date.Add(time.Hour)).
+		Tag("v1.1.0").
+		AddCommit("fix: third", "tauf", date.Add(2*time.Hour))
+
+	releases, err := releaseChangelogs(context.Background(), p, "", "")
+	require.NoError(t, err)
+	require.Len(t, releases, 2)
+	for i, version := range []string{"v1.1.0", "v1.0.0"} {
+		assert.Equal(t, version, releases[i].CurrentVersion)
+		assert.Equal(t, version, releases[i].NextVersion)
+	}
+	require.Len(t, releases[0].Changelog, 1)
+	assert.Equal(t, "second", releases[0].Changelog[0].Title)
+}
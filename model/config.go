@@ -8,4 +8,26 @@ const (
 type Config struct {
 	Version string
 	Pattern string
+	// Labels maps the pull request labels to the changes, DefaultLabelMapping is used when empty
+	Labels map[string]Label
+	// LabelsOverride gives the labels priority over the conventional titles
+	LabelsOverride bool `yaml:"labels_override"`
+}
+
+// LabelMapping returns the label mapping of the configuration
+func (c *Config) LabelMapping() LabelMapping {
+	m := DefaultLabelMapping
+	if c == nil {
+		return m
+	}
+	if len(c.Labels) > 0 {
+		m.Labels = c.Labels
+	}
+	m.Override = c.LabelsOverride
+	return m
+}
+
+// Validate checks the values of the configuration
+func (c *Config) Validate() error {
+	return c.LabelMapping().Validate()
 }
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Label describes the change of a pull request bearing the label.
+// The level defaults to the level of the kind (feat: MINOR, fix: PATCH)
+type Label struct {
+	Kind    string
+	Level   string
+	Section string
+}
+
+// LabelMapping maps the pull request labels to the changes.
+// The labels complete the conventional titles: they give the kind when the title has none,
+// the section, and their level raises the level of the title.
+// With Override, the labels replace the kind and the level read from the title
+type LabelMapping struct {
+	Labels   map[string]Label
+	Override bool
+}
+
+// DefaultLabelMapping knows the usual Github labels
+var DefaultLabelMapping = LabelMapping{
+	Labels: map[string]Label{
+		"breaking":        {Level: ChangeLevelMajor},
+		"breaking-change": {Level: ChangeLevelMajor},
+		"breaking change": {Level: ChangeLevelMajor},
+		"feature":         {Kind: "feat"},
+		"enhancement":     {Kind: "feat"},
+		"bug":             {Kind: "fix"},
+		"bugfix":          {Kind: "fix"},
+		"fix":             {Kind: "fix"},
+	},
+}
+
+// Validate checks the levels of the labels
+func (m LabelMapping) Validate() error {
+	for name, l := range m.Labels {
+		if _, err := parseLevel(l.Level); err != nil {
+			return fmt.Errorf("label %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func (m LabelMapping) lookup(name string) (Label, bool) {
+	for k, l := range m.Labels {
+		if strings.EqualFold(k, name) {
+			return l, true
+		}
+	}
+	return Label{}, false
+}
+
+func (m LabelMapping) apply(ri *ReleaseItem, labels []string) {
+	var (
+		kind, section string
+		level         byte
+		matched       bool
+	)
+	for _, name := range labels {
+		l, ok := m.lookup(name)
+		if !ok {
+			continue
+		}
+		matched = true
+		if kind == "" {
+			kind = strings.ToLower(l.Kind)
+		}
+		if section == "" {
+			section = l.Section
+		}
+		if lv, _ := parseLevel(l.Level); lv > level {
+			level = lv
+		}
+	}
+	if !matched {
+		return
+	}
+
+	ri.Section = section
+	if m.Override && (kind != "" || level != UNDEFINED) {
+		if kind != "" {
+			ri.Kind = kind
+		}
+		ri.Level = maxLevel(changeLevel(kind, false), level)
+		return
+	}
+	// the kind of a label only counts when the title has none
+	if ri.Kind == "" {
+		ri.Kind = kind
+		ri.Level = maxLevel(ri.Level, changeLevel(kind, false))
+	}
+	ri.Level = maxLevel(ri.Level, level)
+}
+
+func maxLevel(a byte, b byte) byte {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// parseLevel reads MAJOR, MINOR or PATCH, empty is UNDEFINED
+func parseLevel(level string) (byte, error) {
+	switch strings.ToUpper(level) {
+	case "":
+		return UNDEFINED, nil
+	case ChangeLevelMajor:
+		return MAJOR, nil
+	case ChangeLevelMinor:
+		return MINOR, nil
+	case ChangeLevelPatch:
+		return PATCH, nil
+	}
+	return UNDEFINED, fmt.Errorf("unknown level %s", level)
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testLabelMapping = LabelMapping{
+	Labels: map[string]Label{
+		"breaking": {Level: ChangeLevelMajor, Section: "Breaking changes"},
+		"feature":  {Kind: "feat", Section: "Features"},
+		"docs":     {Kind: "docs", Level: ChangeLevelPatch},
+		"triage":   {Section: "Triage"},
+	},
+}
+
+func TestLabelMapping_complete(t *testing.T) {
+	ri := NewPullRequestItem("abc", "tauf", testDate, "Add the export", "", PullRequest{Labels: []string{"Feature"}}, testLabelMapping)
+
+	assert.Equal(t, "feat", ri.Kind)
+	assert.Equal(t, byte(MINOR), ri.Level)
+	assert.Equal(t, "Features", ri.Section)
+}
+
+func TestLabelMapping_raiseLevel(t *testing.T) {
+	ri := NewPullRequestItem("abc", "tauf", testDate, "fix: check the token", "", PullRequest{Labels: []string{"breaking"}}, testLabelMapping)
+
+	assert.Equal(t, "fix", ri.Kind)
+	assert.Equal(t, byte(MAJOR), ri.Level)
+	assert.Equal(t, "Breaking changes", ri.Section)
+}
+
+func TestLabelMapping_keepTitle(t *testing.T) {
+	ri := NewPullRequestItem("abc", "tauf", testDate, "feat: add the export", "", PullRequest{Labels: []string{"docs", "unknown"}}, testLabelMapping)
+
+	assert.Equal(t, "feat", ri.Kind)
+	assert.Equal(t, byte(MINOR), ri.Level)
+	assert.Equal(t, "", ri.Section)
+}
+
+func TestLabelMapping_override(t *testing.T) {
+	mapping := testLabelMapping
+	mapping.Override = true
+
+	ri := NewPullRequestItem("abc", "tauf", testDate, "feat: add the export", "", PullRequest{Labels: []string{"docs"}}, mapping)
+	assert.Equal(t, "docs", ri.Kind)
+	assert.Equal(t, byte(PATCH), ri.Level)
+
+	// a label without kind nor level does not override the title
+	ri = NewPullRequestItem("abc", "tauf", testDate, "feat: add the export", "", PullRequest{Labels: []string{"triage"}}, mapping)
+	assert.Equal(t, "feat", ri.Kind)
+	assert.Equal(t, byte(MINOR), ri.Level)
+	assert.Equal(t, "Triage", ri.Section)
+}
+
+func TestLabelMapping_Validate(t *testing.T) {
+	assert.NoError(t, testLabelMapping.Validate())
+	assert.NoError(t, DefaultLabelMapping.Validate())
+	assert.Error(t, LabelMapping{Labels: map[string]Label{"bug": {Level: "HUGE"}}}.Validate())
+}
+
+func TestConfig_LabelMapping(t *testing.T) {
+	var c *Config
+	assert.Equal(t, DefaultLabelMapping, c.LabelMapping())
+
+	c = &Config{LabelsOverride: true}
+	assert.Equal(t, DefaultLabelMapping.Labels, c.LabelMapping().Labels)
+	assert.True(t, c.LabelMapping().Override)
+
+	c = &Config{Labels: testLabelMapping.Labels}
+	assert.Equal(t, testLabelMapping, c.LabelMapping())
+}
+
+func TestLabelMapping_overrideLevel(t *testing.T) {
+	mapping := LabelMapping{Labels: map[string]Label{"minor": {Level: ChangeLevelPatch}, "chore": {Kind: "chore"}}, Override: true}
+
+	ri := NewPullRequestItem("abc", "tauf", testDate, "feat: add the export", "", PullRequest{Labels: []string{"minor"}}, mapping)
+	assert.Equal(t, "feat", ri.Kind)
+	assert.Equal(t, byte(PATCH), ri.Level)
+
+	ri = NewPullRequestItem("abc", "tauf", testDate, "feat: add the export", "", PullRequest{Labels: []string{"chore"}}, mapping)
+	assert.Equal(t, "chore", ri.Kind)
+	assert.Equal(t, byte(UNDEFINED), ri.Level)
+}
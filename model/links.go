@@ -0,0 +1,39 @@
+package model
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Links are the web pages of a repository: {ref} is replaced by a commit hash, {number} by an issue number,
+// {login} by the login of a user. An empty pattern is an unknown page
+type Links struct {
+	Commit string
+	Issue  string
+	// Avatar is the picture of a user
+	Avatar string
+}
+
+// CommitURL returns the page of the commit, empty when unknown
+func (l Links) CommitURL(ref string) string {
+	if l.Commit == "" || ref == "" {
+		return ""
+	}
+	return strings.Replace(l.Commit, "{ref}", ref, -1)
+}
+
+// IssueURL returns the page of the issue, empty when unknown
+func (l Links) IssueURL(number string) string {
+	if l.Issue == "" || number == "" {
+		return ""
+	}
+	return strings.Replace(l.Issue, "{number}", number, -1)
+}
+
+// AvatarURL returns the picture of the user, empty when unknown
+func (l Links) AvatarURL(login string) string {
+	if l.Avatar == "" || login == "" {
+		return ""
+	}
+	return strings.Replace(l.Avatar, "{login}", url.PathEscape(login), -1)
+}
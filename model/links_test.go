@@ -0,0 +1,22 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinks(t *testing.T) {
+	l := Links{Commit: "https://example.com/commit/{ref}", Issue: "https://example.com/issues/{number}"}
+	assert.Equal(t, "https://example.com/commit/abc", l.CommitURL("abc"))
+	assert.Equal(t, "https://example.com/issues/12", l.IssueURL("12"))
+	assert.Equal(t, "", l.CommitURL(""))
+	assert.Equal(t, "", Links{}.IssueURL("12"))
+}
+
+func TestLinks_AvatarURL(t *testing.T) {
+	l := Links{Avatar: "https://github.com/{login}.png"}
+	assert.Equal(t, "https://github.com/tauf.png", l.AvatarURL("tauf"))
+	assert.Equal(t, "https://github.com/a%2Fb.png", l.AvatarURL("a/b"))
+	assert.Equal(t, "", Links{}.AvatarURL("tauf"))
+}
@@ -44,10 +44,11 @@ func (r *Release) NextVersion() (string, error) {
 	return "", errors.New("unknown version calculator")
 }
 
+// MustNextVersion is like NextVersion but panics on error
 func (r *Release) MustNextVersion() string {
 	version, err := r.NextVersion()
 	if err != nil {
-		log.Fatal(err)
+		panic(err)
 	}
 	return version
 }
@@ -15,6 +15,17 @@ type ReleaseItem struct {
 	Level  byte
 	Author string
 	Date   time.Time
+	// Section is the changelog section given by a label, empty when unknown
+	Section string
+	// PullRequest is set when the item comes from a merged pull request instead of a commit
+	PullRequest *PullRequest
+}
+
+// PullRequest is the merged pull request of a changelog item
+type PullRequest struct {
+	Number int
+	URL    string
+	Labels []string
 }
 
 const (
@@ -67,16 +78,31 @@ func NewReleaseItem(id string, author string, date time.Time, message string) Re
 		ri.Title = strings.Trim(fl, "\n ")
 	}
 
+	ri.Level = changeLevel(ri.Kind, strings.Contains(message, "BREAKING CHANGE"))
+
+	return ri
+}
+
+// NewPullRequestItem reads a merged pull request: the title is read like a commit message,
+// then the labels complete it or override it. The body is only the detail,
+// pull request templates often mention breaking changes
+func NewPullRequestItem(id string, author string, date time.Time, title string, body string, pr PullRequest, mapping LabelMapping) ReleaseItem {
+	ri := NewReleaseItem(id, author, date, strings.TrimSpace(title))
+	ri.Detail = strings.TrimSpace(body)
+	ri.PullRequest = &pr
+	mapping.apply(&ri, pr.Labels)
+	return ri
+}
+
+func changeLevel(kind string, breaking bool) byte {
 	switch {
-	case strings.Contains(message, "BREAKING CHANGE"):
-		ri.Level = MAJOR
-	case ri.Kind == "feat":
-		ri.Level = MINOR
-	case ri.Kind == "fix":
-		ri.Level = PATCH
+	case breaking:
+		return MAJOR
+	case kind == "feat":
+		return MINOR
+	case kind == "fix":
+		return PATCH
 	default:
-		ri.Level = UNDEFINED
+		return UNDEFINED
 	}
-
-	return ri
 }
@@ -145,3 +145,35 @@ func TestReleaseItem_LevelName(t *testing.T) {
 		})
 	}
 }
+
+func TestNewPullRequestItem(t *testing.T) {
+	pr := PullRequest{Number: 12, URL: "https://github.com/owner/repo/pull/12", Labels: []string{"Enhancement"}}
+	ri := NewPullRequestItem("abc", "tauf", testDate, "Add the export ", "Closes #3\n\n- [ ] BREAKING CHANGE", pr, DefaultLabelMapping)
+
+	expected := ReleaseItem{
+		ID:          "abc",
+		Kind:        "feat",
+		Title:       "Add the export",
+		Detail:      "Closes #3\n\n- [ ] BREAKING CHANGE",
+		Level:       MINOR,
+		Author:      "tauf",
+		Date:        testDate,
+		PullRequest: &pr,
+	}
+
+	assert.Equal(t, expected, ri)
+}
+
+func TestNewPullRequestItem_conventionalTitle(t *testing.T) {
+	ri := NewPullRequestItem("abc", "tauf", testDate, "fix(api): check the token", "", PullRequest{Number: 12, Labels: []string{"enhancement"}}, DefaultLabelMapping)
+
+	assert.Equal(t, "fix", ri.Kind)
+	assert.Equal(t, "api", ri.Scope)
+	assert.Equal(t, byte(PATCH), ri.Level)
+}
+
+func TestNewPullRequestItem_breakingLabel(t *testing.T) {
+	ri := NewPullRequestItem("abc", "tauf", testDate, "fix: check the token", "", PullRequest{Number: 12, Labels: []string{"breaking-change"}}, DefaultLabelMapping)
+
+	assert.Equal(t, byte(MAJOR), ri.Level)
+}
@@ -65,3 +65,26 @@ func ReadSemver(v string) ([]int64, error) {
 	}
 
 }
+
+// Semver is a semantic version read from a version name
+type Semver struct {
+	Major      int64
+	Minor      int64
+	Patch      int64
+	Prerelease string
+	Build      string
+}
+
+var semverPartsRegexp = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?`)
+
+// FindSemver reads the first major.minor.patch version of the name, the pattern may surround it
+func FindSemver(v string) (Semver, error) {
+	data := semverPartsRegexp.FindStringSubmatch(v)
+	if data == nil {
+		return Semver{}, fmt.Errorf("cannot read version %s", v)
+	}
+	major, _ := strconv.ParseInt(data[1], 10, 0)
+	minor, _ := strconv.ParseInt(data[2], 10, 0)
+	patch, _ := strconv.ParseInt(data[3], 10, 0)
+	return Semver{Major: major, Minor: minor, Patch: patch, Prerelease: data[4], Build: data[5]}, nil
+}
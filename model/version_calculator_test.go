@@ -1,22 +1,22 @@
 package model_test
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/tauffredou/nextver/model"
 	"testing"
-	  "github.com/tauffredou/nextver/model"
-"fmt"
 )
 
 func TestReadSemver(t *testing.T) {
 	tests := []struct {
 		name    string
-		version    string
+		version string
 		want    interface{}
 		wantErr error
 	}{
-		{name: "semver prefix", version: "v1.2.0", want: []int64{1,2,0}},
-		{name: "semver", version: "1.2.0", want: []int64{1,2,0}},
-		{name: "semver", version: "v1.0.1", want: []int64{1,0,1}},
+		{name: "semver prefix", version: "v1.2.0", want: []int64{1, 2, 0}},
+		{name: "semver", version: "1.2.0", want: []int64{1, 2, 0}},
+		{name: "semver", version: "v1.0.1", want: []int64{1, 0, 1}},
 		{name: "date", version: "2006-01-02-150405", wantErr: fmt.Errorf("cannot read version")},
 	}
 
@@ -35,3 +35,27 @@ func TestReadSemver(t *testing.T) {
 
 }
 
+func TestFindSemver(t *testing.T) {
+	tests := []struct {
+		version string
+		want    model.Semver
+		wantErr bool
+	}{
+		{version: "v1.2.3", want: model.Semver{Major: 1, Minor: 2, Patch: 3}},
+		{version: "app-v10.0.1", want: model.Semver{Major: 10, Patch: 1}},
+		{version: "v2.0.0-rc.1+build.5", want: model.Semver{Major: 2, Prerelease: "rc.1", Build: "build.5"}},
+		{version: "2006-01-02-150405", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			semver, err := model.FindSemver(test.version)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, semver)
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package provider
+
+import (
+	"context"
+	"regexp"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tauffredou/nextver/model"
+	"github.com/tauffredou/nextver/sorter"
+	"gopkg.in/yaml.v2"
+)
+
+// apiTag is a tag as returned by a REST API
+type apiTag struct {
+	Name   string
+	Commit string
+}
+
+// apiRepository is the set of primitives a REST API has to offer to compute releases
+type apiRepository interface {
+	projectName() string
+	listTags(ctx context.Context) ([]apiTag, error)
+	defaultBranch(ctx context.Context) (string, error)
+	// readFile returns nil when the file does not exist
+	readFile(ctx context.Context, ref string, path string) ([]byte, error)
+	// isAncestor tells if the tag is reachable from head
+	isAncestor(ctx context.Context, tag apiTag, head string) (bool, error)
+	// history lists the commits reachable from head but not from base, newest first.
+	// base is empty for the whole history
+	history(ctx context.Context, base string, head string) ([]model.ReleaseItem, error)
+}
+
+// apiProvider implements Provider for REST API based providers
+type apiProvider struct {
+	api           apiRepository
+	pattern       string
+	branch        string
+	versionRegexp *regexp.Regexp
+}
+
+func newApiProvider(api apiRepository, pattern string, branch string) *apiProvider {
+	return &apiProvider{
+		api:     api,
+		pattern: pattern,
+		branch:  branch,
+	}
+}
+
+// GetReleases returns the releases sorted by version, the latest first
+func (p *apiProvider) GetReleases(ctx context.Context) ([]model.Release, error) {
+	tags, err := p.releaseTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := make([]model.Release, len(tags))
+	for i := range tags {
+		r[i] = p.tagMapper(tags[i])
+	}
+	return r, nil
+}
+
+// GetRelease get info for the release
+// if name is empty, the changes of the target branch since the last release are returned
+func (p *apiProvider) GetRelease(ctx context.Context, name string) (*model.Release, error) {
+	tags, err := p.releaseTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		head       string
+		candidates = tags
+	)
+	if name == "" {
+		head, err = p.Branch(ctx)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		i := indexOfTag(tags, name)
+		if i == -1 {
+			return nil, &NotFoundError{Resource: "release " + name}
+		}
+		head = tags[i].Commit
+		candidates = tags[i+1:]
+	}
+
+	var previous *apiTag
+	for i := range candidates {
+		ok, err := p.api.isAncestor(ctx, candidates[i], head)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			previous = &candidates[i]
+			break
+		}
+	}
+
+	release := model.Release{
+		Project:        p.api.projectName(),
+		CurrentVersion: name,
+		VersionPattern: p.VersionPattern(ctx),
+	}
+	base := ""
+	if previous != nil {
+		base = previous.Commit
+		if name == "" {
+			release = p.tagMapper(*previous)
+		}
+	}
+
+	release.Changelog, err = p.api.history(ctx, base, head)
+	if err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// Branch get the target branch by order from:
+// 1. configuration
+// 2. repository default branch
+func (p *apiProvider) Branch(ctx context.Context) (string, error) {
+	if p.branch != "" {
+		return p.branch, nil
+	}
+
+	b, err := p.api.defaultBranch(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.branch = b
+	return p.branch, nil
+}
+
+// VersionPattern tries to fetch the config file from the target branch
+func (p *apiProvider) VersionPattern(ctx context.Context) string {
+	if p.pattern != "" {
+		return p.pattern
+	}
+
+	p.pattern = model.DefaultPattern
+	c, err := p.readConfigFile(ctx)
+	if err != nil {
+		log.WithError(err).Warn("Cannot read configuration")
+	} else if c != nil && c.Pattern != "" {
+		p.pattern = c.Pattern
+	}
+	log.WithField("pattern", p.pattern).Debug("got pattern")
+	return p.pattern
+}
+
+func (p *apiProvider) VersionRegexp(ctx context.Context) *regexp.Regexp {
+	if p.versionRegexp == nil {
+		p.versionRegexp = GetVersionRegexp(p.VersionPattern(ctx))
+	}
+	return p.versionRegexp
+}
+
+// Config reads the configuration file of the target branch
+func (p *apiProvider) Config(ctx context.Context) (*model.Config, error) {
+	return p.readConfigFile(ctx)
+}
+
+func (p *apiProvider) readConfigFile(ctx context.Context) (*model.Config, error) {
+	branch, err := p.Branch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := p.api.readFile(ctx, branch, model.DefaultConfigFile)
+	if err != nil || bytes == nil {
+		return nil, err
+	}
+
+	var c model.Config
+	err = yaml.Unmarshal(bytes, &c)
+	if err != nil {
+		return nil, &ConfigurationError{Reason: err.Error()}
+	}
+	return &c, nil
+}
+
+// releaseTags returns the tags matching the version pattern, the latest version first
+func (p *apiProvider) releaseTags(ctx context.Context) ([]apiTag, error) {
+	tags, err := p.api.listTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]model.Release, 0)
+	byName := make(map[string]apiTag)
+	for _, t := range tags {
+		if p.VersionRegexp(ctx).MatchString(t.Name) {
+			releases = append(releases, model.Release{CurrentVersion: t.Name})
+			byName[t.Name] = t
+		}
+	}
+	sort.Sort(sorter.BySemver(releases))
+
+	res := make([]apiTag, len(releases))
+	for i := range releases {
+		res[i] = byName[releases[i].CurrentVersion]
+	}
+	return res, nil
+}
+
+// tagMapper maps the tags returned by releaseTags, the version pattern is already known
+func (p *apiProvider) tagMapper(tag apiTag) model.Release {
+	return model.Release{
+		Project:        p.api.projectName(),
+		CurrentVersion: tag.Name,
+		Ref:            tag.Commit,
+		VersionPattern: p.pattern,
+	}
+}
+
+func indexOfTag(tags []apiTag, name string) int {
+	for i := range tags {
+		if tags[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
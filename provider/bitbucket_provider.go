@@ -0,0 +1,239 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tauffredou/nextver/model"
+)
+
+const (
+	BitbucketCloudHost   = "bitbucket.org"
+	bitbucketCloudApiURL = "https://api.bitbucket.org/2.0"
+	bitbucketPageSize    = 100
+)
+
+type BitbucketProviderConfig struct {
+	Branch  string
+	Pattern string
+	// ApiURL overrides the API endpoint
+	// https://api.bitbucket.org/2.0 for Bitbucket Cloud, https://<host>/rest/api/1.0 for Bitbucket Data Center
+	ApiURL     string
+	HttpClient *http.Client
+}
+
+// BitbucketCloudProvider uses the Bitbucket Cloud REST API (2.0) to fetch data
+type BitbucketCloudProvider struct {
+	*apiProvider
+	client    *restClient
+	Workspace string
+	Repo      string
+}
+
+// NewBitbucketCloudProvider creates a provider for workspace/repo hosted on bitbucket.org.
+// The token is either an access token or username:app-password
+func NewBitbucketCloudProvider(workspace string, repo string, token string, config *BitbucketProviderConfig) (*BitbucketCloudProvider, error) {
+	if workspace == "" || repo == "" || config == nil {
+		return nil, &ConfigurationError{}
+	}
+
+	apiURL := config.ApiURL
+	if apiURL == "" {
+		apiURL = bitbucketCloudApiURL
+	}
+	log.WithField("url", apiURL).WithField("token", obfuscateToken(token)).Debug("Init bitbucket cloud provider")
+
+	p := &BitbucketCloudProvider{
+		client:    newBitbucketClient(apiURL, token, config.HttpClient),
+		Workspace: workspace,
+		Repo:      repo,
+	}
+	p.apiProvider = newApiProvider(p, config.Pattern, config.Branch)
+	return p, nil
+}
+
+// newBitbucketClient authenticates with basic auth when the token is username:password, with a bearer token otherwise
+func newBitbucketClient(apiURL string, token string, httpClient *http.Client) *restClient {
+	client := newRestClient(apiURL, httpClient)
+	if i := strings.Index(token, ":"); i != -1 {
+		req := http.Request{Header: http.Header{}}
+		req.SetBasicAuth(token[:i], token[i+1:])
+		client.header.Set("Authorization", req.Header.Get("Authorization"))
+	} else if token != "" {
+		client.header.Set("Authorization", "Bearer "+token)
+	}
+	return client
+}
+
+// CreateRelease creates an annotated tag on ref, Bitbucket has no release object.
+// ref must be a commit hash
+func (p *BitbucketCloudProvider) CreateRelease(ctx context.Context, name string, ref string, description string) error {
+	body := map[string]interface{}{
+		"name":    name,
+		"message": description,
+		"target":  map[string]string{"hash": ref},
+	}
+	_, err := p.client.post(ctx, p.repoPath("/refs/tags"), body, nil)
+	return err
+}
+
+type bitbucketCloudCommits struct {
+	Values []struct {
+		Hash    string    `json:"hash"`
+		Message string    `json:"message"`
+		Date    time.Time `json:"date"`
+		Author  struct {
+			Raw  string `json:"raw"`
+			User struct {
+				DisplayName string `json:"display_name"`
+			} `json:"user"`
+		} `json:"author"`
+		Parents []struct {
+			Hash string `json:"hash"`
+		} `json:"parents"`
+	} `json:"values"`
+	Next string `json:"next"`
+}
+
+func (p *BitbucketCloudProvider) projectName() string { return p.Workspace + "/" + p.Repo }
+
+func (p *BitbucketCloudProvider) Links() model.Links {
+	base := "https://" + BitbucketCloudHost + "/" + p.Workspace + "/" + p.Repo
+	return model.Links{Commit: base + "/commits/{ref}", Issue: base + "/issues/{number}"}
+}
+
+func (p *BitbucketCloudProvider) repoPath(path string) string {
+	return "/repositories/" + url.PathEscape(p.Workspace) + "/" + url.PathEscape(p.Repo) + path
+}
+
+func (p *BitbucketCloudProvider) listTags(ctx context.Context) ([]apiTag, error) {
+	tags := make([]apiTag, 0)
+	for next := p.repoPath("/refs/tags"); next != ""; {
+		var resp struct {
+			Values []struct {
+				Name   string `json:"name"`
+				Target struct {
+					Hash string `json:"hash"`
+				} `json:"target"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		_, err := p.client.get(ctx, next, bitbucketCloudPage(next), &resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range resp.Values {
+			tags = append(tags, apiTag{Name: t.Name, Commit: t.Target.Hash})
+		}
+		next = resp.Next
+	}
+	return tags, nil
+}
+
+func (p *BitbucketCloudProvider) defaultBranch(ctx context.Context) (string, error) {
+	var resp struct {
+		MainBranch struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+	}
+	_, err := p.client.get(ctx, p.repoPath(""), nil, &resp)
+	return resp.MainBranch.Name, err
+}
+
+// readFile reads the file at the commit of the ref: the slashes of a branch name would be read as directories
+func (p *BitbucketCloudProvider) readFile(ctx context.Context, ref string, path string) ([]byte, error) {
+	commit, err := p.resolveCommit(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var content []byte
+	_, err = p.client.get(ctx, p.repoPath("/src/"+url.PathEscape(commit)+"/"+escapePath(path)), nil, &content)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// resolveCommit gets the commit of a branch, a tag or a commit
+func (p *BitbucketCloudProvider) resolveCommit(ctx context.Context, ref string) (string, error) {
+	var resp bitbucketCloudCommits
+	_, err := p.client.get(ctx, p.repoPath("/commits"), url.Values{
+		"include": {ref},
+		"pagelen": {"1"},
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Values) == 0 {
+		return "", &NotFoundError{Resource: "ref " + ref}
+	}
+	return resp.Values[0].Hash, nil
+}
+
+// isAncestor checks that no commit of the tag is missing from head
+func (p *BitbucketCloudProvider) isAncestor(ctx context.Context, tag apiTag, head string) (bool, error) {
+	var resp bitbucketCloudCommits
+	_, err := p.client.get(ctx, p.repoPath("/commits/"+url.PathEscape(tag.Commit)), url.Values{
+		"exclude": {head},
+		"pagelen": {"1"},
+	}, &resp)
+	if err != nil {
+		return false, err
+	}
+	return len(resp.Values) == 0, nil
+}
+
+func (p *BitbucketCloudProvider) history(ctx context.Context, base string, head string) ([]model.ReleaseItem, error) {
+	result := make([]model.ReleaseItem, 0)
+
+	// the refs are query parameters, a branch name can hold slashes
+	for next := p.repoPath("/commits"); next != ""; {
+		query := bitbucketCloudPage(next)
+		if query != nil {
+			query.Set("include", head)
+			if base != "" {
+				query.Set("exclude", base)
+			}
+		}
+		var resp bitbucketCloudCommits
+		_, err := p.client.get(ctx, next, query, &resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range resp.Values {
+			/* filter merge commit */
+			if len(c.Parents) < 2 {
+				author := c.Author.User.DisplayName
+				if author == "" {
+					author = c.Author.Raw
+				}
+				result = append(result, model.NewReleaseItem(c.Hash, author, c.Date, c.Message))
+			}
+		}
+		next = resp.Next
+	}
+	return result, nil
+}
+
+// escapePath escapes the segments of a file path, the slashes are kept
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+// bitbucketCloudPage adds the page size to the first request, next links already hold it
+func bitbucketCloudPage(next string) url.Values {
+	if strings.Contains(next, "://") {
+		return nil
+	}
+	return url.Values{"pagelen": {strconv.Itoa(bitbucketPageSize)}}
+}
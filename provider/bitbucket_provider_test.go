@@ -0,0 +1,265 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBitbucketCloud serves a repository with the fakeHistory
+func fakeBitbucketCloud(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/2.0/repositories/workspace/repo", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]interface{}{"mainbranch": map[string]string{"name": "master"}})
+	})
+	mux.HandleFunc("/2.0/repositories/workspace/repo/refs/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var body map[string]interface{}
+			if !readJSON(t, w, r, &body) {
+				return
+			}
+			assert.Equal(t, "v1.2.0", body["name"])
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		user, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "app-password", password)
+		if r.URL.Query().Get("page") == "" {
+			writeJSON(t, w, map[string]interface{}{
+				"values": []interface{}{
+					map[string]interface{}{"name": "v1.0.0", "target": map[string]string{"hash": "c1"}},
+					map[string]interface{}{"name": "v1.1.0", "target": map[string]string{"hash": "c3"}},
+				},
+				"next": server.URL + "/2.0/repositories/workspace/repo/refs/tags?page=2",
+			})
+			return
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"values": []interface{}{
+				map[string]interface{}{"name": "v2.0.0", "target": map[string]string{"hash": "c5"}},
+			},
+		})
+	})
+	mux.HandleFunc("/2.0/repositories/workspace/repo/src/", func(w http.ResponseWriter, r *http.Request) {
+		// the file is read at a commit, a branch name with slashes would be ambiguous
+		commit := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/2.0/repositories/workspace/repo/src/"), "/", 2)[0]
+		if _, ok := fakeMessages[commit]; !assert.True(t, ok, "commit expected, got %s", commit) {
+			http.Error(w, "commit expected", http.StatusBadRequest)
+			return
+		}
+		http.NotFound(w, r)
+	})
+	commits := func(w http.ResponseWriter, r *http.Request, head string) {
+		values := make([]interface{}, 0)
+		for _, c := range fakeCommitsBetween(r.URL.Query().Get("exclude"), head) {
+			values = append(values, map[string]interface{}{
+				"hash":    c,
+				"message": fakeMessages[c],
+				"date":    fakeDate.Format(time.RFC3339),
+				"author":  map[string]interface{}{"raw": "tauf <tauf@example.com>", "user": map[string]string{"display_name": "tauf"}},
+				"parents": []interface{}{},
+			})
+		}
+		if pagelen := r.URL.Query().Get("pagelen"); pagelen == "1" && len(values) > 1 {
+			values = values[:1]
+		}
+		writeJSON(t, w, map[string]interface{}{"values": values})
+	}
+	mux.HandleFunc("/2.0/repositories/workspace/repo/commits/", func(w http.ResponseWriter, r *http.Request) {
+		commits(w, r, strings.TrimPrefix(r.URL.Path, "/2.0/repositories/workspace/repo/commits/"))
+	})
+	mux.HandleFunc("/2.0/repositories/workspace/repo/commits", func(w http.ResponseWriter, r *http.Request) {
+		commits(w, r, r.URL.Query().Get("include"))
+	})
+
+	server = httptest.NewServer(mux)
+	return server
+}
+
+// fakeBitbucketServer serves a repository with the fakeHistory
+func fakeBitbucketServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/1.0/projects/PRJ/repos/repo/branches/default", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]string{"displayId": "master"})
+	})
+	mux.HandleFunc("/rest/api/1.0/projects/PRJ/repos/repo/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var body map[string]string
+			if !readJSON(t, w, r, &body) {
+				return
+			}
+			assert.Equal(t, "v1.2.0", body["name"])
+			assert.Equal(t, "master", body["startPoint"])
+			writeJSON(t, w, body)
+			return
+		}
+
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		if r.URL.Query().Get("start") == "0" {
+			writeJSON(t, w, map[string]interface{}{
+				"values": []interface{}{
+					map[string]interface{}{"displayId": "v2.0.0", "latestCommit": "c5"},
+					map[string]interface{}{"displayId": "v1.1.0", "latestCommit": "c3"},
+				},
+				"isLastPage":    false,
+				"nextPageStart": 2,
+			})
+			return
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"values":     []interface{}{map[string]interface{}{"displayId": "v1.0.0", "latestCommit": "c1"}},
+			"isLastPage": true,
+		})
+	})
+	mux.HandleFunc("/rest/api/1.0/projects/PRJ/repos/repo/raw/.nextver/config.yml", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "master", r.URL.Query().Get("at"))
+		mustWrite(w, "pattern: vSEMVER\n")
+	})
+	mux.HandleFunc("/rest/api/1.0/projects/PRJ/repos/repo/commits", func(w http.ResponseWriter, r *http.Request) {
+		values := make([]interface{}, 0)
+		for _, c := range fakeCommitsBetween(r.URL.Query().Get("since"), r.URL.Query().Get("until")) {
+			values = append(values, map[string]interface{}{
+				"id":              c,
+				"message":         fakeMessages[c],
+				"author":          map[string]string{"name": "tauf", "displayName": "Tauf"},
+				"authorTimestamp": fakeDate.Unix() * 1000,
+				"parents":         []interface{}{},
+			})
+		}
+		writeJSON(t, w, map[string]interface{}{"values": values, "isLastPage": true})
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newFakeBitbucketCloudProvider(t *testing.T, server *httptest.Server) *BitbucketCloudProvider {
+	p, err := NewBitbucketCloudProvider("workspace", "repo", "user:app-password", &BitbucketProviderConfig{
+		ApiURL: server.URL + "/2.0",
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func newFakeBitbucketServerProvider(t *testing.T, server *httptest.Server) *BitbucketServerProvider {
+	p, err := NewBitbucketServerProvider("", "PRJ", "repo", "secret", &BitbucketProviderConfig{
+		ApiURL: server.URL + "/rest/api/1.0",
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestBitbucketCloudProvider_GetReleases(t *testing.T) {
+	server := fakeBitbucketCloud(t)
+	defer server.Close()
+
+	actual, err := newFakeBitbucketCloudProvider(t, server).GetReleases(context.Background())
+	require.NoError(t, err)
+	require.Len(t, actual, 3)
+	assert.Equal(t, "v2.0.0", actual[0].CurrentVersion)
+	assert.Equal(t, "v1.1.0", actual[1].CurrentVersion)
+	assert.Equal(t, "workspace/repo", actual[1].Project)
+}
+
+func TestBitbucketCloudProvider_GetRelease_next(t *testing.T) {
+	server := fakeBitbucketCloud(t)
+	defer server.Close()
+
+	actual, err := newFakeBitbucketCloudProvider(t, server).GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", actual.CurrentVersion)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "feature 4", actual.Changelog[0].Title)
+	assert.Equal(t, "tauf", actual.Changelog[0].Author)
+}
+
+func TestBitbucketCloudProvider_GetRelease_branchWithSlash(t *testing.T) {
+	server := fakeBitbucketCloud(t)
+	defer server.Close()
+
+	p, err := NewBitbucketCloudProvider("workspace", "repo", "user:app-password", &BitbucketProviderConfig{
+		ApiURL: server.URL + "/2.0",
+		Branch: "release/1.x",
+	})
+	require.NoError(t, err)
+
+	actual, err := p.GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", actual.CurrentVersion)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "hotfix 6", actual.Changelog[0].Title)
+}
+
+func TestEscapePath(t *testing.T) {
+	assert.Equal(t, ".nextver/config.yml", escapePath(".nextver/config.yml"))
+	assert.Equal(t, "docs/a%20b%3F.md", escapePath("docs/a b?.md"))
+}
+
+func TestBitbucketCloudProvider_GetRelease_named(t *testing.T) {
+	server := fakeBitbucketCloud(t)
+	defer server.Close()
+
+	actual, err := newFakeBitbucketCloudProvider(t, server).GetRelease(context.Background(), "v1.1.0")
+	require.NoError(t, err)
+	require.Len(t, actual.Changelog, 2)
+	assert.Equal(t, "fix 3", actual.Changelog[0].Title)
+	assert.Equal(t, "feature 2", actual.Changelog[1].Title)
+}
+
+func TestBitbucketCloudProvider_CreateRelease(t *testing.T) {
+	server := fakeBitbucketCloud(t)
+	defer server.Close()
+
+	assert.NoError(t, newFakeBitbucketCloudProvider(t, server).CreateRelease(context.Background(), "v1.2.0", "c4", "changelog"))
+}
+
+func TestBitbucketServerProvider_GetReleases(t *testing.T) {
+	server := fakeBitbucketServer(t)
+	defer server.Close()
+
+	actual, err := newFakeBitbucketServerProvider(t, server).GetReleases(context.Background())
+	require.NoError(t, err)
+	require.Len(t, actual, 3)
+	assert.Equal(t, "v2.0.0", actual[0].CurrentVersion)
+	assert.Equal(t, "v1.0.0", actual[2].CurrentVersion)
+	assert.Equal(t, "c1", actual[2].Ref)
+}
+
+func TestBitbucketServerProvider_GetRelease_next(t *testing.T) {
+	server := fakeBitbucketServer(t)
+	defer server.Close()
+
+	actual, err := newFakeBitbucketServerProvider(t, server).GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", actual.CurrentVersion)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "feature 4", actual.Changelog[0].Title)
+	assert.Equal(t, "Tauf", actual.Changelog[0].Author)
+	assert.Equal(t, int64(1561466141), actual.Changelog[0].Date.Unix())
+}
+
+func TestBitbucketServerProvider_GetRelease_named(t *testing.T) {
+	server := fakeBitbucketServer(t)
+	defer server.Close()
+
+	actual, err := newFakeBitbucketServerProvider(t, server).GetRelease(context.Background(), "v2.0.0")
+	require.NoError(t, err)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "feature 5", actual.Changelog[0].Title)
+}
+
+func TestBitbucketServerProvider_CreateRelease(t *testing.T) {
+	server := fakeBitbucketServer(t)
+	defer server.Close()
+
+	assert.NoError(t, newFakeBitbucketServerProvider(t, server).CreateRelease(context.Background(), "v1.2.0", "master", "changelog"))
+}
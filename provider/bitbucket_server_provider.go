@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tauffredou/nextver/model"
+)
+
+// BitbucketServerProvider uses the Bitbucket Data Center (Server) REST API (1.0) to fetch data
+type BitbucketServerProvider struct {
+	*apiProvider
+	client  *restClient
+	Host    string
+	Project string
+	Repo    string
+}
+
+// NewBitbucketServerProvider creates a provider for the repository project/repo hosted on host.
+// The token is either an HTTP access token or username:password
+func NewBitbucketServerProvider(host string, project string, repo string, token string, config *BitbucketProviderConfig) (*BitbucketServerProvider, error) {
+	if project == "" || repo == "" || config == nil || (host == "" && config.ApiURL == "") {
+		return nil, &ConfigurationError{}
+	}
+
+	apiURL := config.ApiURL
+	if apiURL == "" {
+		apiURL = "https://" + host + "/rest/api/1.0"
+	}
+	log.WithField("url", apiURL).WithField("token", obfuscateToken(token)).Debug("Init bitbucket server provider")
+
+	p := &BitbucketServerProvider{
+		client:  newBitbucketClient(apiURL, token, config.HttpClient),
+		Host:    host,
+		Project: project,
+		Repo:    repo,
+	}
+	p.apiProvider = newApiProvider(p, config.Pattern, config.Branch)
+	return p, nil
+}
+
+// CreateRelease creates an annotated tag on ref, Bitbucket has no release object
+func (p *BitbucketServerProvider) CreateRelease(ctx context.Context, name string, ref string, description string) error {
+	body := map[string]string{
+		"name":       name,
+		"startPoint": ref,
+		"message":    description,
+	}
+	_, err := p.client.post(ctx, p.repoPath("/tags"), body, nil)
+	return err
+}
+
+// bitbucketServerPage is the paging envelope of the API
+type bitbucketServerPage struct {
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+type bitbucketServerCommits struct {
+	bitbucketServerPage
+	Values []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		Author  struct {
+			Name        string `json:"name"`
+			DisplayName string `json:"displayName"`
+		} `json:"author"`
+		AuthorTimestamp int64 `json:"authorTimestamp"`
+		Parents         []struct {
+			ID string `json:"id"`
+		} `json:"parents"`
+	} `json:"values"`
+}
+
+func (p *BitbucketServerProvider) projectName() string { return p.Project + "/" + p.Repo }
+
+// Links has no issue page, Bitbucket Data Center uses Jira
+func (p *BitbucketServerProvider) Links() model.Links {
+	if p.Host == "" {
+		return model.Links{}
+	}
+	return model.Links{Commit: "https://" + p.Host + p.repoPath("/commits/{ref}")}
+}
+
+func (p *BitbucketServerProvider) repoPath(path string) string {
+	return "/projects/" + url.PathEscape(p.Project) + "/repos/" + url.PathEscape(p.Repo) + path
+}
+
+func (p *BitbucketServerProvider) listTags(ctx context.Context) ([]apiTag, error) {
+	tags := make([]apiTag, 0)
+	for start := 0; ; {
+		var resp struct {
+			bitbucketServerPage
+			Values []struct {
+				DisplayID    string `json:"displayId"`
+				LatestCommit string `json:"latestCommit"`
+			} `json:"values"`
+		}
+		_, err := p.client.get(ctx, p.repoPath("/tags"), bitbucketServerQuery(start), &resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range resp.Values {
+			tags = append(tags, apiTag{Name: t.DisplayID, Commit: t.LatestCommit})
+		}
+		if resp.IsLastPage {
+			return tags, nil
+		}
+		start = resp.NextPageStart
+	}
+}
+
+func (p *BitbucketServerProvider) defaultBranch(ctx context.Context) (string, error) {
+	var resp struct {
+		DisplayID string `json:"displayId"`
+	}
+	_, err := p.client.get(ctx, p.repoPath("/branches/default"), nil, &resp)
+	return resp.DisplayID, err
+}
+
+func (p *BitbucketServerProvider) readFile(ctx context.Context, ref string, path string) ([]byte, error) {
+	var content []byte
+	_, err := p.client.get(ctx, p.repoPath("/raw/"+path), url.Values{"at": {ref}}, &content)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// isAncestor checks that no commit of the tag is missing from head
+func (p *BitbucketServerProvider) isAncestor(ctx context.Context, tag apiTag, head string) (bool, error) {
+	var resp bitbucketServerCommits
+	_, err := p.client.get(ctx, p.repoPath("/commits"), url.Values{
+		"until": {tag.Commit},
+		"since": {head},
+		"limit": {"1"},
+	}, &resp)
+	if err != nil {
+		return false, err
+	}
+	return len(resp.Values) == 0, nil
+}
+
+func (p *BitbucketServerProvider) history(ctx context.Context, base string, head string) ([]model.ReleaseItem, error) {
+	result := make([]model.ReleaseItem, 0)
+	for start := 0; ; {
+		query := bitbucketServerQuery(start)
+		query.Set("until", head)
+		if base != "" {
+			query.Set("since", base)
+		}
+
+		var resp bitbucketServerCommits
+		_, err := p.client.get(ctx, p.repoPath("/commits"), query, &resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range resp.Values {
+			/* filter merge commit */
+			if len(c.Parents) < 2 {
+				author := c.Author.DisplayName
+				if author == "" {
+					author = c.Author.Name
+				}
+				date := time.Unix(0, c.AuthorTimestamp*int64(time.Millisecond))
+				result = append(result, model.NewReleaseItem(c.ID, author, date, c.Message))
+			}
+		}
+		if resp.IsLastPage {
+			return result, nil
+		}
+		start = resp.NextPageStart
+	}
+}
+
+func bitbucketServerQuery(start int) url.Values {
+	return url.Values{
+		"start": {strconv.Itoa(start)},
+		"limit": {strconv.Itoa(bitbucketPageSize)},
+	}
+}
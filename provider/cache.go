@@ -0,0 +1,314 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores the API responses. The responses are memoized for the life of the process,
+// they are also written in Dir when it is set, and reused by the next processes until TTL expires.
+// Expired responses having an ETag are revalidated instead of downloaded again
+type Cache struct {
+	Dir string
+	TTL time.Duration
+
+	mu     sync.Mutex
+	memory map[string]*cacheRecord
+}
+
+// CacheEntry describes a response stored on disk
+type CacheEntry struct {
+	Request  string
+	StoredAt time.Time
+	ETag     string
+	Size     int
+	Expired  bool
+}
+
+// cacheRecord is a response as stored on disk
+type cacheRecord struct {
+	Request  string      `json:"request"`
+	StoredAt time.Time   `json:"stored_at"`
+	ETag     string      `json:"etag,omitempty"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+}
+
+// NewCache creates a cache, in memory only when dir is empty
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{
+		Dir:    dir,
+		TTL:    ttl,
+		memory: make(map[string]*cacheRecord),
+	}
+}
+
+// Transport caches the responses of base
+func (c *Cache) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &cacheTransport{cache: c, base: base}
+}
+
+// Entries lists the responses stored on disk, the most recent first
+func (c *Cache) Entries() ([]CacheEntry, error) {
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]CacheEntry, 0, len(files))
+	for _, f := range files {
+		r, err := readCacheRecord(f)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, CacheEntry{
+			Request:  r.Request,
+			StoredAt: r.StoredAt,
+			ETag:     r.ETag,
+			Size:     len(r.Body),
+			Expired:  !c.fresh(r),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].StoredAt.After(entries[j].StoredAt) })
+	return entries, nil
+}
+
+// Clear removes the responses from memory and from disk
+func (c *Cache) Clear() error {
+	c.forget()
+
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) files() ([]string, error) {
+	if c.Dir == "" {
+		return nil, nil
+	}
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (c *Cache) fresh(r *cacheRecord) bool {
+	return time.Since(r.StoredAt) < c.TTL
+}
+
+// lookup returns the stored response and tells if it can be used without asking the server
+func (c *Cache) lookup(key string) (*cacheRecord, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r, ok := c.memory[key]; ok {
+		return r, true
+	}
+	if c.Dir == "" {
+		return nil, false
+	}
+
+	r, err := readCacheRecord(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	if c.fresh(r) {
+		c.memory[key] = r
+		return r, true
+	}
+	return r, false
+}
+
+func (c *Cache) store(key string, r *cacheRecord) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.memory[key] = r
+	if c.Dir == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path(key), b, 0600)
+}
+
+// forget drops the memoized responses, the data may have changed
+func (c *Cache) forget() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.memory = make(map[string]*cacheRecord)
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+func readCacheRecord(path string) (*cacheRecord, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r cacheRecord
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *cacheRecord) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(r.Status),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+type cacheTransport struct {
+	cache *Cache
+	base  http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	r := req.WithContext(req.Context())
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	if body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if !isCacheable(req, body) {
+		t.cache.forget()
+		return t.base.RoundTrip(r)
+	}
+
+	key := cacheKey(req, body)
+	stored, fresh := t.cache.lookup(key)
+	if fresh {
+		return stored.response(req), nil
+	}
+	if stored != nil && stored.ETag != "" {
+		r.Header.Set("If-None-Match", stored.ETag)
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && stored != nil:
+		_ = resp.Body.Close()
+		stored.StoredAt = time.Now()
+		return stored.response(req), t.cache.store(key, stored)
+	case resp.StatusCode == http.StatusOK:
+		b, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		record := &cacheRecord{
+			Request:  describeRequest(req, body),
+			StoredAt: time.Now(),
+			ETag:     resp.Header.Get("ETag"),
+			Status:   resp.StatusCode,
+			Header:   resp.Header,
+			Body:     b,
+		}
+		if req.Method == http.MethodPost && hasGraphqlErrors(b) {
+			return record.response(req), nil
+		}
+		return record.response(req), t.cache.store(key, record)
+	}
+	return resp, nil
+}
+
+// isCacheable accepts the reads: GET requests and graphql queries
+func isCacheable(req *http.Request, body []byte) bool {
+	switch req.Method {
+	case http.MethodGet:
+		return true
+	case http.MethodPost:
+		var q struct {
+			Query string `json:"query"`
+		}
+		if json.Unmarshal(body, &q) != nil || q.Query == "" {
+			return false
+		}
+		return !strings.HasPrefix(strings.TrimSpace(q.Query), "mutation")
+	}
+	return false
+}
+
+// hasGraphqlErrors tells if the graphql response reports errors. A missing object or a rate limit
+// is answered with a 200 status, the next run must query again
+func hasGraphqlErrors(body []byte) bool {
+	var r struct {
+		Errors []json.RawMessage `json:"errors"`
+	}
+	return json.Unmarshal(body, &r) == nil && len(r.Errors) > 0
+}
+
+// cacheKey identifies the request: the url holds the repository and the ref, or the body for graphql.
+// The credentials are part of the key, another token may not see the same data
+func cacheKey(req *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, s := range []string{req.Method, req.URL.String(), req.Header.Get("Authorization"), req.Header.Get("PRIVATE-TOKEN")} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func describeRequest(req *http.Request, body []byte) string {
+	s := req.Method + " " + req.URL.String()
+	if len(body) > 0 {
+		s += " " + string(body)
+	}
+	return s
+}
@@ -0,0 +1,217 @@
+package provider
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeETagServer counts the requests and answers 304 when the ETag matches
+func fakeETagServer(calls *int32, revalidated *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(revalidated, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		mustWrite(w, "content of "+r.URL.Path)
+	}))
+}
+
+func tempCacheDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "nextver-cache")
+	require.NoError(t, err)
+	return dir, func() { _ = os.RemoveAll(dir) }
+}
+
+func cachedGet(t *testing.T, client *http.Client, u string) string {
+	resp, err := client.Get(u)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestCache_memoize(t *testing.T) {
+	var calls, revalidated int32
+	server := fakeETagServer(&calls, &revalidated)
+	defer server.Close()
+
+	client := &http.Client{Transport: NewCache("", 0).Transport(nil)}
+	assert.Equal(t, "content of /tags", cachedGet(t, client, server.URL+"/tags"))
+	assert.Equal(t, "content of /tags", cachedGet(t, client, server.URL+"/tags"))
+	assert.Equal(t, "content of /branch", cachedGet(t, client, server.URL+"/branch"))
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestCache_writeForgets(t *testing.T) {
+	var calls, revalidated int32
+	server := fakeETagServer(&calls, &revalidated)
+	defer server.Close()
+
+	client := &http.Client{Transport: NewCache("", 0).Transport(nil)}
+	cachedGet(t, client, server.URL+"/tags")
+	resp, err := client.Post(server.URL+"/tags", "application/json", strings.NewReader(`{"name":"v1.0.0"}`))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	cachedGet(t, client, server.URL+"/tags")
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestCache_disk(t *testing.T) {
+	var calls, revalidated int32
+	server := fakeETagServer(&calls, &revalidated)
+	defer server.Close()
+	dir, remove := tempCacheDir(t)
+	defer remove()
+
+	cachedGet(t, &http.Client{Transport: NewCache(dir, time.Hour).Transport(nil)}, server.URL+"/tags")
+	assert.Equal(t, "content of /tags", cachedGet(t, &http.Client{Transport: NewCache(dir, time.Hour).Transport(nil)}, server.URL+"/tags"))
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestCache_revalidate(t *testing.T) {
+	var calls, revalidated int32
+	server := fakeETagServer(&calls, &revalidated)
+	defer server.Close()
+	dir, remove := tempCacheDir(t)
+	defer remove()
+
+	cachedGet(t, &http.Client{Transport: NewCache(dir, 0).Transport(nil)}, server.URL+"/tags")
+	assert.Equal(t, "content of /tags", cachedGet(t, &http.Client{Transport: NewCache(dir, 0).Transport(nil)}, server.URL+"/tags"))
+	assert.Equal(t, int32(2), calls)
+	assert.Equal(t, int32(1), revalidated)
+}
+
+func TestCache_keyedByToken(t *testing.T) {
+	var calls, revalidated int32
+	server := fakeETagServer(&calls, &revalidated)
+	defer server.Close()
+
+	client := &http.Client{Transport: NewCache("", 0).Transport(nil)}
+	for _, token := range []string{"token1", "token2"} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/tags", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestCache_EntriesAndClear(t *testing.T) {
+	var calls, revalidated int32
+	server := fakeETagServer(&calls, &revalidated)
+	defer server.Close()
+	dir, remove := tempCacheDir(t)
+	defer remove()
+
+	cache := NewCache(dir, time.Hour)
+	client := &http.Client{Transport: cache.Transport(nil)}
+	cachedGet(t, client, server.URL+"/tags")
+
+	entries, err := cache.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "GET "+server.URL+"/tags", entries[0].Request)
+	assert.Equal(t, `"v1"`, entries[0].ETag)
+	assert.Equal(t, len("content of /tags"), entries[0].Size)
+	assert.False(t, entries[0].Expired)
+
+	require.NoError(t, cache.Clear())
+	entries, err = cache.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	cachedGet(t, client, server.URL+"/tags")
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestCache_graphqlErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		mustWrite(w, `{"data":{"repository":null},"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`)
+	}))
+	defer server.Close()
+	dir, remove := tempCacheDir(t)
+	defer remove()
+
+	cache := NewCache(dir, time.Hour)
+	client := &http.Client{Transport: cache.Transport(nil)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(server.URL+"/graphql", "application/json", strings.NewReader(`{"query":"{viewer{login}}"}`))
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		require.NoError(t, err)
+		assert.Contains(t, string(b), "RATE_LIMITED")
+	}
+	assert.Equal(t, int32(2), calls)
+
+	entries, err := cache.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestHasGraphqlErrors(t *testing.T) {
+	assert.True(t, hasGraphqlErrors([]byte(`{"errors":[{"type":"NOT_FOUND"}]}`)))
+	assert.False(t, hasGraphqlErrors([]byte(`{"data":{"viewer":{"login":"tauf"}}}`)))
+	assert.False(t, hasGraphqlErrors([]byte(`{"data":{},"errors":[]}`)))
+	assert.False(t, hasGraphqlErrors([]byte(`[{"name":"v1.0.0"}]`)))
+}
+
+func TestIsCacheable(t *testing.T) {
+	tests := []struct {
+		method string
+		body   string
+		want   bool
+	}{
+		{http.MethodGet, "", true},
+		{http.MethodPost, `{"query":"query($owner:String!){repository(owner:$owner){id}}"}`, true},
+		{http.MethodPost, `{"query":"{viewer{login}}"}`, true},
+		{http.MethodPost, `{"query":"mutation{createRef{clientMutationId}}"}`, false},
+		{http.MethodPost, `{"name":"v1.0.0"}`, false},
+		{http.MethodDelete, "", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		assert.Equal(t, tt.want, isCacheable(req, []byte(tt.body)), tt.body)
+	}
+}
+
+func TestGithubProvider_cache(t *testing.T) {
+	var calls int32
+	server := fakeFlakyGithub(t, &calls)
+	defer server.Close()
+	dir, remove := tempCacheDir(t)
+	defer remove()
+
+	for i := 0; i < 2; i++ {
+		p, err := NewGithubProvider("owner", "repo", "token", &GithubProviderConfig{
+			ApiURL:  server.URL + "/api/v3",
+			Pattern: "vSEMVER",
+			Cache:   NewCache(dir, time.Hour),
+		})
+		require.NoError(t, err)
+		for j := 0; j < 2; j++ {
+			actual, err := p.GetReleases(context.Background())
+			require.NoError(t, err)
+			assert.NotEmpty(t, actual)
+		}
+	}
+	assert.Equal(t, int32(1), calls)
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ConfigurationError is returned when a provider cannot be configured, or when its configuration file is invalid
+type ConfigurationError struct {
+	// Reason is empty for a missing mandatory parameter
+	Reason string
+}
+
+func (e *ConfigurationError) Error() string {
+	if e.Reason == "" {
+		return "Invalid configuration"
+	}
+	return "Invalid configuration: " + e.Reason
+}
+
+// NotFoundError is returned when the repository, the release or the ref does not exist
+type NotFoundError struct {
+	Resource string
+	Err      error
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s not found", e.Resource)
+	}
+	return fmt.Sprintf("%s not found: %s", e.Resource, e.Err)
+}
+
+func (e *NotFoundError) Unwrap() error { return e.Err }
+
+// AuthenticationError is returned when the credentials are missing, invalid or not allowed to read the repository
+type AuthenticationError struct {
+	Err error
+}
+
+func (e *AuthenticationError) Error() string {
+	return fmt.Sprintf("authentication failed: %s", e.Err)
+}
+
+func (e *AuthenticationError) Unwrap() error { return e.Err }
+
+// RateLimitError is returned when the API rate limit is exceeded.
+// Reset is zero when the API does not tell when the limit is lifted
+type RateLimitError struct {
+	Reset time.Time
+	Err   error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return fmt.Sprintf("rate limit exceeded: %s", e.Err)
+	}
+	return fmt.Sprintf("rate limit exceeded until %s: %s", e.Reset.Format(time.RFC3339), e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error { return e.Err }
+
+// IsNotFound tells if err is a NotFoundError
+func IsNotFound(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
+// IsAuthenticationError tells if err is an AuthenticationError
+func IsAuthenticationError(err error) bool {
+	_, ok := err.(*AuthenticationError)
+	return ok
+}
+
+// IsRateLimitError tells if err is a RateLimitError
+func IsRateLimitError(err error) bool {
+	_, ok := err.(*RateLimitError)
+	return ok
+}
+
+// IsConfigurationError tells if err is a ConfigurationError
+func IsConfigurationError(err error) bool {
+	_, ok := err.(*ConfigurationError)
+	return ok
+}
+
+// classifyAPIError turns the error statuses of the REST APIs into typed errors
+func classifyAPIError(e *APIError, header http.Header) error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return &NotFoundError{Resource: e.URL, Err: e}
+	case http.StatusUnauthorized:
+		return &AuthenticationError{Err: e}
+	case http.StatusTooManyRequests:
+		return &RateLimitError{Reset: rateLimitReset(header), Err: e}
+	case http.StatusForbidden:
+		if header.Get("X-RateLimit-Remaining") == "0" || header.Get("RateLimit-Remaining") == "0" {
+			return &RateLimitError{Reset: rateLimitReset(header), Err: e}
+		}
+		return &AuthenticationError{Err: e}
+	}
+	return e
+}
+
+// rateLimitReset reads the reset time from the Retry-After (seconds) or the X-RateLimit-Reset (epoch) headers
+func rateLimitReset(header http.Header) time.Time {
+	if d, ok := retryAfter(header); ok {
+		return time.Now().Add(d)
+	}
+	var seconds int64
+	for _, h := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		if _, err := fmt.Sscan(header.Get(h), &seconds); err == nil {
+			return time.Unix(seconds, 0)
+		}
+	}
+	return time.Time{}
+}
+
+// retryAfter reads the Retry-After header, in seconds
+func retryAfter(header http.Header) (time.Duration, bool) {
+	var seconds int64
+	if _, err := fmt.Sscan(header.Get("Retry-After"), &seconds); err != nil {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// classifyGithubError turns the errors of the graphql client into typed errors.
+// The client only exposes messages: non-200 statuses and the errors of the graphql response
+func classifyGithubError(err error) error {
+	if err == nil {
+		return nil
+	}
+	// the errors of the transport are wrapped by the http client
+	if e, ok := err.(*url.Error); ok {
+		if _, ok := e.Err.(*RateLimitError); ok {
+			return e.Err
+		}
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "non-200 OK status code: 401"), strings.Contains(msg, "Bad credentials"):
+		return &AuthenticationError{Err: err}
+	case strings.Contains(strings.ToLower(msg), "rate limit"):
+		return &RateLimitError{Err: err}
+	case strings.Contains(msg, "non-200 OK status code: 403"):
+		return &AuthenticationError{Err: err}
+	case strings.Contains(msg, "Could not resolve to"), strings.Contains(msg, "non-200 OK status code: 404"):
+		return &NotFoundError{Resource: "github resource", Err: err}
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+	"github.com/tauffredou/nextver/provider/providertest"
+)
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		check  func(error) bool
+	}{
+		{"not found", http.StatusNotFound, http.Header{}, IsNotFound},
+		{"unauthorized", http.StatusUnauthorized, http.Header{}, IsAuthenticationError},
+		{"forbidden", http.StatusForbidden, http.Header{}, IsAuthenticationError},
+		{"rate limit", http.StatusForbidden, http.Header{"X-Ratelimit-Remaining": {"0"}}, IsRateLimitError},
+		{"too many requests", http.StatusTooManyRequests, http.Header{}, IsRateLimitError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := classifyAPIError(&APIError{StatusCode: test.status}, test.header)
+			assert.True(t, test.check(err), err.Error())
+		})
+	}
+
+	err := classifyAPIError(&APIError{StatusCode: http.StatusBadGateway}, http.Header{})
+	assert.IsType(t, &APIError{}, err)
+}
+
+func TestClassifyAPIError_reset(t *testing.T) {
+	err := classifyAPIError(&APIError{StatusCode: http.StatusForbidden}, http.Header{
+		"X-Ratelimit-Remaining": {"0"},
+		"X-Ratelimit-Reset":     {"1561466141"},
+	})
+	require.IsType(t, &RateLimitError{}, err)
+	assert.Equal(t, time.Unix(1561466141, 0), err.(*RateLimitError).Reset)
+}
+
+func TestClassifyGithubError(t *testing.T) {
+	assert.Nil(t, classifyGithubError(nil))
+	assert.True(t, IsAuthenticationError(classifyGithubError(errors.New("non-200 OK status code: 401 Unauthorized body: \"\""))))
+	assert.True(t, IsRateLimitError(classifyGithubError(errors.New("API rate limit exceeded for user ID 1."))))
+	assert.True(t, IsNotFound(classifyGithubError(errors.New("Could not resolve to a Repository with the name 'owner/repo'."))))
+	assert.False(t, IsNotFound(classifyGithubError(errors.New("timeout"))))
+}
+
+func TestGithubProvider_GetReleases_unauthorized(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "Bad credentials", http.StatusUnauthorized)
+	})
+	p := &GithubProvider{client: mockGithubClient(mux), config: &GithubProviderConfig{Pattern: "vSEMVER"}}
+
+	_, err := p.GetReleases(context.Background())
+	assert.True(t, IsAuthenticationError(err))
+}
+
+func TestGitProvider_GetRelease_unknown(t *testing.T) {
+	b := providertest.NewGitRepositoryBuilder(t)
+	defer b.Remove()
+	b.AddCommit("feat: first", "tauf", testDate).Tag("v1.0.0")
+
+	_, err := NewGitProvider(b.Path, "vSEMVER").GetRelease(context.Background(), "v9.0.0")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestGitProvider_ReadConfigFile_invalid(t *testing.T) {
+	b := providertest.NewGitRepositoryBuilder(t)
+	defer b.Remove()
+	require.NoError(t, os.MkdirAll(filepath.Join(b.Path, ".nextver"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(b.Path, model.DefaultConfigFile), []byte("pattern: [\n"), 0644))
+
+	_, err := NewGitProvider(b.Path, "").ReadConfigFile()
+	assert.True(t, IsConfigurationError(err))
+}
+
+func TestGiteaProvider_GetRelease_unknown(t *testing.T) {
+	server := fakeGitea(t)
+	defer server.Close()
+
+	_, err := newFakeGiteaProvider(t, server).GetRelease(context.Background(), "v9.0.0")
+	assert.True(t, IsNotFound(err))
+}
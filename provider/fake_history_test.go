@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeHistory is the history served by the fake servers of the REST providers, newest commit first
+//
+//	c1 (v1.0.0) - c2 - c3 (v1.1.0) - c4 (master, main)
+//	                                |- c5 (v2.0.0)
+//	                                \- c6 (release/1.x)
+var fakeHistory = map[string][]string{
+	"master":      {"c4", "c3", "c2", "c1"},
+	"main":        {"c4", "c3", "c2", "c1"},
+	"c5":          {"c5", "c3", "c2", "c1"},
+	"c6":          {"c6", "c3", "c2", "c1"},
+	"release/1.x": {"c6", "c3", "c2", "c1"},
+	"c4":          {"c4", "c3", "c2", "c1"},
+	"c3":          {"c3", "c2", "c1"},
+	"c2":          {"c2", "c1"},
+	"c1":          {"c1"},
+}
+
+var fakeMessages = map[string]string{
+	"c1": "Initial commit",
+	"c2": "feat: feature 2",
+	"c3": "fix: fix 3",
+	"c4": "feat(api): feature 4\n\nwith details",
+	"c5": "feat: feature 5\n\nBREAKING CHANGE: api",
+	"c6": "fix: hotfix 6",
+}
+
+// fakeDate is the date of every commit
+var fakeDate = time.Date(2019, 6, 25, 12, 35, 41, 0, time.UTC)
+
+// fakeCommitsBetween lists the commits reachable from head but not from base, newest first
+func fakeCommitsBetween(base string, head string) []string {
+	excluded := map[string]bool{}
+	for _, c := range fakeHistory[base] {
+		excluded[c] = true
+	}
+	res := make([]string, 0)
+	for _, c := range fakeHistory[head] {
+		if !excluded[c] {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// fakeIsAncestor tells if the commit is reachable from the ref
+func fakeIsAncestor(commit string, ref string) bool {
+	for _, c := range fakeHistory[ref] {
+		if c == commit {
+			return true
+		}
+	}
+	return false
+}
+
+// oldestFirst reverses a list of commits
+func oldestFirst(commits []string) []string {
+	res := make([]string, len(commits))
+	for i, c := range commits {
+		res[len(commits)-1-i] = c
+	}
+	return res
+}
+
+// writeJSON runs in the handler goroutine: the failures are reported, the test goes on
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	assert.NoError(t, json.NewEncoder(w).Encode(v))
+}
+
+// readJSON decodes the request body, a bad request is answered when it is invalid
+func readJSON(t *testing.T, w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if !assert.NoError(t, json.NewDecoder(r.Body).Decode(v)) {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"github.com/tauffredou/nextver/model"
@@ -20,6 +21,10 @@ type GitProvider struct {
 	path           string
 	versionPattern string
 	versionRegexp  *regexp.Regexp
+	// branch is the target branch, HEAD when empty
+	branch string
+	// repository is set when the repository is not read from path (in-memory clone)
+	repository *git.Repository
 }
 
 func (p *GitProvider) String() string {
@@ -34,6 +39,12 @@ func NewGitProvider(path string, versionPattern string) *GitProvider {
 	return &provider
 }
 
+// Branch sets the target branch, a local branch or a branch of the origin remote. HEAD is read otherwise
+func (p *GitProvider) Branch(branch string) *GitProvider {
+	p.branch = branch
+	return p
+}
+
 func (p *GitProvider) VersionRegexp() *regexp.Regexp {
 	if p.versionRegexp == nil {
 		p.versionRegexp = GetVersionRegexp(p.VersionPattern())
@@ -41,9 +52,16 @@ func (p *GitProvider) VersionRegexp() *regexp.Regexp {
 	return p.versionRegexp
 }
 
+func (p *GitProvider) openRepository() (*git.Repository, error) {
+	if p.repository != nil {
+		return p.repository, nil
+	}
+	return git.PlainOpen(p.path)
+}
+
 // GetReleases get sorted release list
-func (p *GitProvider) GetReleases() ([]model.Release, error) {
-	repo, err := git.PlainOpen(p.path)
+func (p *GitProvider) GetReleases(ctx context.Context) ([]model.Release, error) {
+	repo, err := p.openRepository()
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +73,9 @@ func (p *GitProvider) GetReleases() ([]model.Release, error) {
 	}
 
 	err = t.ForEach(func(reference *plumbing.Reference) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if p.tagFilter(reference) {
 			tag := p.tagMapper(reference)
 			r = append(r, tag)
@@ -70,7 +91,7 @@ func (p *GitProvider) GetReleases() ([]model.Release, error) {
 }
 
 // GetRelease get info for the release
-func (p *GitProvider) GetRelease(name string) (*model.Release, error) {
+func (p *GitProvider) GetRelease(ctx context.Context, name string) (*model.Release, error) {
 	var (
 		err error
 		ref *plumbing.Reference
@@ -82,70 +103,58 @@ func (p *GitProvider) GetRelease(name string) (*model.Release, error) {
 		VersionPattern: p.VersionPattern(),
 	}
 
-	repo, err := git.PlainOpen(p.path)
+	repo, err := p.openRepository()
 	if err != nil {
 		return nil, err
 	}
 
 	if name != "" {
-		ref, _ = repo.Tag(name)
+		ref, err = repo.Tag(name)
+		if err == git.ErrTagNotFound {
+			return nil, &NotFoundError{Resource: "release " + name}
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	previousRelease := p.getPreviousRelease(name)
-	var prevObject *object.Tag
+	previousRelease, err := p.getPreviousRelease(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var prevCommit *object.Commit
 	if previousRelease != nil {
 		if name == "" {
 			release = *previousRelease
 		}
-		prev, _ := repo.Tag(previousRelease.CurrentVersion)
-		prevObject, err = repo.TagObject(prev.Hash())
+		prevCommit, err = resolveTagCommit(repo, previousRelease.CurrentVersion)
 		if err != nil {
-			log.WithError(err).Warnf("Incomplete tag %s", prev.Name())
+			log.WithError(err).Warnf("Incomplete tag %s", previousRelease.CurrentVersion)
 		}
-	} else {
-		//if name != "" {
-		//	return nil, errors.New("unknown release")
-		//}
 	}
 
-	var options git.LogOptions
+	var target *object.Commit
 	if ref != nil {
-		refObject, err := repo.TagObject(ref.Hash())
-		if err != nil {
-			return nil, err
-		}
-		options = git.LogOptions{
-			From:  refObject.Target,
-			Order: git.LogOrderCommitterTime,
-		}
+		target, err = resolveTagCommit(repo, name)
 	} else {
-		options = git.LogOptions{
-			Order: git.LogOrderCommitterTime,
-		}
+		target, err = p.targetCommit(repo)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	it, err := repo.Log(&options)
+	commits, err := commitRange(ctx, target, prevCommit)
 	if err != nil {
 		return nil, err
 	}
 
-	release.Changelog = mapChangelog(it, prevObject)
-	it.Close()
+	release.Changelog = mapChangelog(commits)
 	return &release, nil
 }
 
-func mapChangelog(it object.CommitIter, prevObject *object.Tag) []model.ReleaseItem {
+func mapChangelog(commits []*object.Commit) []model.ReleaseItem {
 	changelog := make([]model.ReleaseItem, 0)
-	for {
-		commit, err := it.Next()
-		if err != nil {
-			break
-		}
-
-		if prevObject != nil && commit.Hash == prevObject.Target {
-			break
-		}
-
+	for _, commit := range commits {
 		/* filter merge commit */
 		if len(commit.ParentHashes) < 2 {
 			item := mapToReleaseItem(commit)
@@ -155,6 +164,69 @@ func mapChangelog(it object.CommitIter, prevObject *object.Tag) []model.ReleaseI
 	return changelog
 }
 
+// commitRange lists the commits reachable from target but not from exclude (exclude..target).
+// Commits are sorted topologically: a commit always comes before its parents,
+// the most recent committer time first when several commits are available.
+func commitRange(ctx context.Context, target *object.Commit, exclude *object.Commit) ([]*object.Commit, error) {
+	excluded := make(map[plumbing.Hash]bool)
+	if exclude != nil {
+		var err error
+		excluded, err = reachableCommits(ctx, exclude)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]*object.Commit, 0)
+	if excluded[target.Hash] {
+		return result, nil
+	}
+
+	commits := make(map[plumbing.Hash]*object.Commit)
+	err := object.NewCommitPreorderIter(target, excluded, nil).ForEach(func(c *object.Commit) error {
+		commits[c.Hash] = c
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[plumbing.Hash]int)
+	for _, c := range commits {
+		for _, h := range c.ParentHashes {
+			if _, ok := commits[h]; ok {
+				children[h]++
+			}
+		}
+	}
+
+	ready := []*object.Commit{target}
+	for len(ready) > 0 {
+		next := 0
+		for i := range ready {
+			if ready[i].Committer.When.After(ready[next].Committer.When) {
+				next = i
+			}
+		}
+		c := ready[next]
+		ready = append(ready[:next], ready[next+1:]...)
+		result = append(result, c)
+
+		for _, h := range c.ParentHashes {
+			parent, ok := commits[h]
+			if !ok {
+				continue
+			}
+			children[h]--
+			if children[h] == 0 {
+				ready = append(ready, parent)
+			}
+		}
+	}
+
+	return result, nil
+}
+
 func mapToReleaseItem(commit *object.Commit) model.ReleaseItem {
 	return model.NewReleaseItem(commit.Hash.String(), commit.Author.Name, commit.Author.When, commit.Message)
 }
@@ -173,35 +245,131 @@ func (p *GitProvider) tagMapper(reference *plumbing.Reference) model.Release {
 }
 
 // getPreviousRelease calculates the release before
-// if release parameter is empty, then it returns the last release
-func (p *GitProvider) getPreviousRelease(release string) *model.Release {
-	releases, err := p.GetReleases()
+// if release parameter is empty, then it returns the last release reachable from the target branch.
+// Only releases that are ancestors of the target are considered, so a maintenance
+// branch is not compared to the releases of another branch.
+func (p *GitProvider) getPreviousRelease(ctx context.Context, release string) (*model.Release, error) {
+	releases, err := p.GetReleases(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	l := len(releases)
-	if l == 0 {
-		return nil
+	if len(releases) == 0 {
+		return nil, nil
 	}
-	if l == 1 {
-		return &releases[0]
+
+	repo, err := p.openRepository()
+	if err != nil {
+		return nil, err
 	}
-	for i := 0; i < l-1; i++ {
-		it := releases[i]
-		it.VersionPattern = p.VersionPattern()
-		if release == "" {
-			return &it
+
+	var target *object.Commit
+	candidates := releases
+	if release == "" {
+		target, err = p.targetCommit(repo)
+		switch {
+		case IsNotFound(err):
+			return nil, err
+		case err != nil:
+			log.WithError(err).Debug("Cannot resolve HEAD")
+			return nil, nil
+		}
+	} else {
+		i := indexOfRelease(releases, release)
+		if i == -1 {
+			return nil, nil
+		}
+		target, err = resolveTagCommit(repo, release)
+		if err != nil {
+			return nil, err
 		}
+		candidates = releases[i+1:]
+	}
 
-		if it.CurrentVersion == release {
-			r := releases[i+1]
+	ancestors, err := reachableCommits(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range candidates {
+		c, err := resolveTagCommit(repo, candidates[i].CurrentVersion)
+		if err != nil {
+			log.WithError(err).Warnf("Incomplete tag %s", candidates[i].CurrentVersion)
+			continue
+		}
+		if ancestors[c.Hash] {
+			r := candidates[i]
 			r.VersionPattern = p.VersionPattern()
-			return &r
+			return &r, nil
 		}
 	}
 
-	return nil
+	return nil, nil
+}
+
+// targetCommit is the commit of the target branch, the branch of the origin remote when there is no local one.
+// It is the commit of HEAD without target branch
+func (p *GitProvider) targetCommit(repo *git.Repository) (*object.Commit, error) {
+	if p.branch == "" {
+		return headCommit(repo)
+	}
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(p.branch), true)
+	if err == plumbing.ErrReferenceNotFound {
+		ref, err = repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, p.branch), true)
+	}
+	if err == plumbing.ErrReferenceNotFound {
+		return nil, &NotFoundError{Resource: "branch " + p.branch}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo.CommitObject(ref.Hash())
+}
+
+func headCommit(repo *git.Repository) (*object.Commit, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	return repo.CommitObject(head.Hash())
+}
+
+func indexOfRelease(releases []model.Release, name string) int {
+	for i := range releases {
+		if releases[i].CurrentVersion == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveTagCommit returns the commit targeted by a tag, annotated or lightweight
+func resolveTagCommit(repo *git.Repository, name string) (*object.Commit, error) {
+	ref, err := repo.Tag(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := repo.TagObject(ref.Hash())
+	switch err {
+	case nil:
+		return tag.Commit()
+	case plumbing.ErrObjectNotFound:
+		return repo.CommitObject(ref.Hash())
+	default:
+		return nil, err
+	}
+}
+
+// reachableCommits lists the commits that are ancestors of c, c included
+func reachableCommits(ctx context.Context, c *object.Commit) (map[plumbing.Hash]bool, error) {
+	seen := make(map[plumbing.Hash]bool)
+	err := object.NewCommitPreorderIter(c, nil, nil).ForEach(func(commit *object.Commit) error {
+		seen[commit.Hash] = true
+		return ctx.Err()
+	})
+	return seen, err
 }
 
 //VersionPattern tries to fetch the config file
@@ -214,11 +382,27 @@ func (p *GitProvider) VersionPattern() string {
 	if err == nil && c.Pattern != "" {
 		return c.Pattern
 	}
+	if IsConfigurationError(err) {
+		log.WithError(err).Warn("Cannot read configuration")
+	}
 
 	return model.DefaultPattern
 }
 
+// Config reads the configuration file of the repository
+func (p *GitProvider) Config(ctx context.Context) (*model.Config, error) {
+	c, err := p.ReadConfigFile()
+	if os.IsNotExist(err) || err == object.ErrFileNotFound {
+		return nil, nil
+	}
+	return c, err
+}
+
 func (p *GitProvider) ReadConfigFile() (*model.Config, error) {
+	if p.repository != nil {
+		return p.readConfigFileFromHead()
+	}
+
 	//log.Debug("provider.GitProvider::ReadConfigFile")
 	f := filepath.Join(p.path, model.DefaultConfigFile)
 
@@ -227,10 +411,13 @@ func (p *GitProvider) ReadConfigFile() (*model.Config, error) {
 		return nil, err
 	}
 	bytes, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
 	var c model.Config
 	err = yaml.Unmarshal(bytes, &c)
 	if err != nil {
-		log.Fatal(err)
+		return nil, &ConfigurationError{Reason: err.Error()}
 	}
 	return &c, nil
 }
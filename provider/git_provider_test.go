@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -13,10 +14,12 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/tauffredou/nextver/model"
+	"github.com/tauffredou/nextver/provider/providertest"
 	"gopkg.in/src-d/go-billy.v4/osfs"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"gopkg.in/src-d/go-git.v4/storage/filesystem"
 )
 
@@ -61,7 +64,7 @@ func TestProviderSuite(t *testing.T) {
 
 func (suite *ProviderSuite) TestGitProvider_GetReleases() {
 	p := suite.provider
-	actual, err := p.GetReleases()
+	actual, err := p.GetReleases(context.Background())
 	require.NoError(suite.T(), err)
 	require.Equal(suite.T(), 4, len(actual))
 	assert.Equal(suite.T(), "v1.10.0", actual[0].CurrentVersion)
@@ -82,7 +85,8 @@ func (suite *ProviderSuite) TestGitProvider_getPreviousRelease() {
 	}
 	for _, tt := range tests {
 		suite.T().Run(tt.name, func(t *testing.T) {
-			previousRelease := p.getPreviousRelease(tt.name)
+			previousRelease, err := p.getPreviousRelease(context.Background(), tt.name)
+			require.NoError(t, err)
 			if tt.want == nil {
 				assert.Nil(t, previousRelease)
 			} else {
@@ -102,17 +106,119 @@ func TestGitProvider_getPreviousRelease_noPreviousRelease(t *testing.T) {
 	_, _ = git.Init(filesystem.NewStorage(fs, cache.NewObjectLRUDefault()), fs)
 
 	p := NewGitProvider(outputDir, "vSEMVER")
-	assert.Nil(t, p.getPreviousRelease("any"))
+	previous, err := p.getPreviousRelease(context.Background(), "any")
+	assert.NoError(t, err)
+	assert.Nil(t, previous)
+}
+
+func TestGitProvider_getPreviousRelease_maintenanceBranch(t *testing.T) {
+	outputDir, _ := ioutil.TempDir("", "nextver-test-")
+	defer os.RemoveAll(outputDir)
+
+	repo, err := git.PlainInit(outputDir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	first := commitFile(t, wt, "a", "feat: first")
+	_, err = repo.CreateTag("v1.0.0", first, nil)
+	require.NoError(t, err)
+	second := commitFile(t, wt, "b", "feat!: second\n\nBREAKING CHANGE: major")
+	_, err = repo.CreateTag("v2.0.0", second, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{
+		Hash:   first,
+		Branch: plumbing.NewBranchReferenceName("release/1.x"),
+		Create: true,
+	}))
+	backport := commitFile(t, wt, "c", "fix: backport")
+	_, err = repo.CreateTag("v1.0.1", backport, nil)
+	require.NoError(t, err)
+	commitFile(t, wt, "d", "fix: another backport")
+
+	p := NewGitProvider(outputDir, "vSEMVER")
+
+	previous, err := p.getPreviousRelease(context.Background(), "")
+	require.NoError(t, err)
+	if assert.NotNil(t, previous) {
+		assert.Equal(t, "v1.0.1", previous.CurrentVersion)
+	}
+
+	previous, err = p.getPreviousRelease(context.Background(), "v2.0.0")
+	require.NoError(t, err)
+	if assert.NotNil(t, previous) {
+		assert.Equal(t, "v1.0.0", previous.CurrentVersion)
+	}
+}
+
+func TestGitProvider_GetRelease_mergedBranch(t *testing.T) {
+	outputDir, _ := ioutil.TempDir("", "nextver-test-")
+	defer os.RemoveAll(outputDir)
+
+	repo, err := git.PlainInit(outputDir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	date := testDate
+	master := plumbing.NewBranchReferenceName("master")
+	feature := plumbing.NewBranchReferenceName("feature")
+
+	commitFileAt(t, wt, "a", "feat: init", date)
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Branch: feature, Create: true}))
+	featureCommit := commitFileAt(t, wt, "b", "feat: feature", date.Add(time.Hour))
+
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Branch: master}))
+	tagged := commitFileAt(t, wt, "c", "fix: bug", date.Add(2*time.Hour))
+	_, err = repo.CreateTag("v1.0.0", tagged, nil)
+	require.NoError(t, err)
+	after := commitFileAt(t, wt, "d", "fix: after", date.Add(3*time.Hour))
+
+	_, err = wt.Commit("Merge branch 'feature'", &git.CommitOptions{
+		Author:  &object.Signature{Name: "tauf", Email: "tauf@example.com", When: date.Add(4 * time.Hour)},
+		Parents: []plumbing.Hash{after, featureCommit},
+	})
+	require.NoError(t, err)
+
+	p := NewGitProvider(outputDir, "vSEMVER")
+	r, err := p.GetRelease(context.Background(), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1.0.0", r.CurrentVersion)
+	require.Len(t, r.Changelog, 2)
+	assert.Equal(t, "after", r.Changelog[0].Title)
+	assert.Equal(t, "feature", r.Changelog[1].Title)
+}
+
+func commitFile(t *testing.T, wt *git.Worktree, name string, message string) plumbing.Hash {
+	return commitFileAt(t, wt, name, message, time.Now())
+}
+
+func commitFileAt(t *testing.T, wt *git.Worktree, name string, message string, when time.Time) plumbing.Hash {
+	f, err := wt.Filesystem.Create(name)
+	require.NoError(t, err)
+	_, err = f.Write([]byte(message))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = wt.Add(name)
+	require.NoError(t, err)
+	h, err := wt.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "tauf", Email: "tauf@example.com", When: when},
+	})
+	require.NoError(t, err)
+	return h
 }
 
 func (suite *ProviderSuite) TestGitProvider_GetReleases_badPath() {
 	p := NewGitProvider("badPath", "vSEMVER")
-	_, err := p.GetReleases()
+	_, err := p.GetReleases(context.Background())
 	assert.Error(suite.T(), err)
 }
 
 func (suite *ProviderSuite) TestGitProvider_GetNextRelease() {
-	actual, err := suite.provider.GetRelease("")
+	actual, err := suite.provider.GetRelease(context.Background(), "")
 	require.NoError(suite.T(), err)
 	require.NotNil(suite.T(), actual)
 	assert.Equal(suite.T(), "v1.11.0", actual.MustNextVersion())
@@ -120,14 +226,14 @@ func (suite *ProviderSuite) TestGitProvider_GetNextRelease() {
 
 func (suite *ProviderSuite) TestGitProvider_GetRelease_empty() {
 	p := suite.provider
-	actual, err := p.GetRelease("")
+	actual, err := p.GetRelease(context.Background(), "")
 	require.NoError(suite.T(), err)
 	require.NotNil(suite.T(), actual)
 }
 
 func (suite *ProviderSuite) TestGitProvider_GetRelease_oldest() {
 	p := suite.provider
-	actual, err := p.GetRelease("v1.0.1")
+	actual, err := p.GetRelease(context.Background(), "v1.0.1")
 	require.NoError(suite.T(), err)
 	require.NotNil(suite.T(), actual)
 
@@ -139,7 +245,7 @@ func (suite *ProviderSuite) TestGitProvider_GetRelease_oldest() {
 
 func (suite *ProviderSuite) TestProvider_GetRelease_withBoundaries() {
 	p := suite.provider
-	r, err := p.GetRelease("v1.1.0")
+	r, err := p.GetRelease(context.Background(), "v1.1.0")
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), r)
@@ -232,3 +338,96 @@ func TestGitProvider_GetVersionRegexp_date(t *testing.T) {
 		})
 	}
 }
+
+func TestGitProvider_Config(t *testing.T) {
+	b := providertest.NewGitRepositoryBuilder(t)
+	defer b.Remove()
+
+	c, err := NewGitProvider(b.Path, "").Config(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, c, "no configuration file")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(b.Path, ".nextver"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(b.Path, model.DefaultConfigFile), []byte("pattern: app-vSEMVER\n"), 0644))
+
+	c, err = NewGitProvider(b.Path, "").Config(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "app-vSEMVER", c.Pattern)
+}
+
+func TestGitProvider_mergedBranch(t *testing.T) {
+	b := providertest.NewGitRepositoryBuilder(t)
+	defer b.Remove()
+
+	b.AddCommit("feat: first", "tauf", testDate).
+		Tag("v1.0.0").
+		Branch("feature").
+		AddCommit("feat: feature", "tauf", testDate.Add(time.Hour)).
+		Checkout("master").
+		AddCommit("fix: bug", "tauf", testDate.Add(2*time.Hour)).
+		AnnotatedTag("v1.0.1", "release v1.0.1").
+		Merge("feature", "Merge branch 'feature'", "tauf", testDate.Add(3*time.Hour))
+
+	p := NewGitProvider(b.Path, "vSEMVER")
+	releases, err := p.GetReleases(context.Background())
+	require.NoError(t, err)
+	require.Len(t, releases, 2)
+	assert.Equal(t, "v1.0.1", releases[0].CurrentVersion)
+
+	actual, err := p.GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.1", actual.CurrentVersion)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "feature", actual.Changelog[0].Title)
+	assert.Equal(t, "tauf", actual.Changelog[0].Author)
+	assert.Equal(t, "v1.1.0", actual.MustNextVersion())
+}
+
+func TestGitProvider_GetRelease_cancelled(t *testing.T) {
+	b := providertest.NewGitRepositoryBuilder(t)
+	defer b.Remove()
+	b.AddCommit("feat: first", "tauf", testDate).
+		Tag("v1.0.0").
+		AddCommit("fix: second", "tauf", testDate.Add(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewGitProvider(b.Path, "vSEMVER").GetRelease(ctx, "")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestGitProvider_GetRelease_branch(t *testing.T) {
+	b := providertest.NewGitRepositoryBuilder(t)
+	defer b.Remove()
+	b.AddCommit("feat: first", "tauf", testDate).
+		Tag("v1.0.0").
+		Branch("release/1.0.x").
+		AddCommit("fix: maintenance", "tauf", testDate.Add(time.Hour)).
+		Checkout("master").
+		AddCommit("feat: second", "tauf", testDate.Add(2*time.Hour)).
+		Tag("v1.1.0").
+		AddCommit("fix: third", "tauf", testDate.Add(3*time.Hour))
+
+	actual, err := NewGitProvider(b.Path, "vSEMVER").Branch("release/1.0.x").GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", actual.CurrentVersion)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "maintenance", actual.Changelog[0].Title)
+	assert.Equal(t, "v1.0.1", actual.MustNextVersion())
+
+	actual, err = NewGitProvider(b.Path, "vSEMVER").GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", actual.CurrentVersion)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "third", actual.Changelog[0].Title)
+}
+
+func TestGitProvider_GetRelease_unknownBranch(t *testing.T) {
+	b := providertest.NewGitRepositoryBuilder(t)
+	defer b.Remove()
+	b.AddCommit("feat: first", "tauf", testDate).Tag("v1.0.0")
+
+	_, err := NewGitProvider(b.Path, "vSEMVER").Branch("missing").GetRelease(context.Background(), "")
+	assert.True(t, IsNotFound(err), "%v", err)
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/provider/providertest"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestNewRemoteGitProvider(t *testing.T) {
+	outputDir, _ := ioutil.TempDir("", "nextver-test-")
+	defer os.RemoveAll(outputDir)
+
+	repo, err := git.PlainInit(outputDir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commitFile(t, wt, ".nextver/config.yml", "pattern: SEMVER")
+	first := commitFile(t, wt, "a", "feat: first")
+	_, err = repo.CreateTag("1.0.0", first, nil)
+	require.NoError(t, err)
+	commitFile(t, wt, "b", "fix: second")
+
+	p, err := NewRemoteGitProvider(context.Background(), "file://"+outputDir, "", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "SEMVER", p.VersionPattern())
+
+	releases, err := p.GetReleases(context.Background())
+	require.NoError(t, err)
+	require.Len(t, releases, 1)
+	assert.Equal(t, "1.0.0", releases[0].CurrentVersion)
+
+	r, err := p.GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", r.CurrentVersion)
+	require.Len(t, r.Changelog, 1)
+	assert.Equal(t, "second", r.Changelog[0].Title)
+	assert.Equal(t, "1.0.1", r.MustNextVersion())
+}
+
+func TestNewRemoteGitProvider_unreachable(t *testing.T) {
+	_, err := NewRemoteGitProvider(context.Background(), "file:///non/existing/repository", "vSEMVER", "")
+	assert.Error(t, err)
+}
+
+func TestNewRemoteGitProvider_branch(t *testing.T) {
+	b := providertest.NewGitRepositoryBuilder(t)
+	defer b.Remove()
+	b.AddCommit("feat: first", "tauf", testDate).
+		Tag("v1.0.0").
+		Branch("release/1.0.x").
+		AddCommit("fix: maintenance", "tauf", testDate.Add(time.Hour)).
+		Checkout("master").
+		AddCommit("feat: second", "tauf", testDate.Add(2*time.Hour)).
+		Tag("v1.1.0")
+
+	p, err := NewRemoteGitProvider(context.Background(), "file://"+b.Path, "vSEMVER", "release/1.0.x")
+	require.NoError(t, err)
+
+	r, err := p.GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", r.CurrentVersion)
+	require.Len(t, r.Changelog, 1)
+	assert.Equal(t, "maintenance", r.Changelog[0].Title)
+
+	// the other branches are not fetched
+	_, err = p.repository.Reference(plumbing.NewBranchReferenceName("master"), true)
+	assert.Equal(t, plumbing.ErrReferenceNotFound, err)
+
+	_, err = NewRemoteGitProvider(context.Background(), "file://"+b.Path, "vSEMVER", "missing")
+	assert.True(t, IsNotFound(err), "%v", err)
+}
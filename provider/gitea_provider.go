@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tauffredou/nextver/model"
+)
+
+const giteaPageSize = 50
+
+// GiteaProvider uses the Gitea REST API (v1) to fetch data. Forgejo shares the same API
+type GiteaProvider struct {
+	*apiProvider
+	client *restClient
+	Host   string
+	Owner  string
+	Repo   string
+}
+
+type GiteaProviderConfig struct {
+	Branch  string
+	Pattern string
+	// ApiURL overrides the API endpoint, https://<host>/api/v1 by default
+	ApiURL     string
+	HttpClient *http.Client
+}
+
+// NewGiteaProvider creates a provider for owner/repo hosted on host.
+// The token is optional for public repositories
+func NewGiteaProvider(host string, owner string, repo string, token string, config *GiteaProviderConfig) (*GiteaProvider, error) {
+	if owner == "" || repo == "" || config == nil || (host == "" && config.ApiURL == "") {
+		return nil, &ConfigurationError{}
+	}
+
+	apiURL := config.ApiURL
+	if apiURL == "" {
+		apiURL = "https://" + host + "/api/v1"
+	}
+	log.WithField("url", apiURL).WithField("token", obfuscateToken(token)).Debug("Init gitea provider")
+
+	client := newRestClient(apiURL, config.HttpClient)
+	if token != "" {
+		client.header.Set("Authorization", "token "+token)
+	}
+
+	p := &GiteaProvider{
+		client: client,
+		Host:   host,
+		Owner:  owner,
+		Repo:   repo,
+	}
+	p.apiProvider = newApiProvider(p, config.Pattern, config.Branch)
+	return p, nil
+}
+
+// CreateRelease creates the tag on ref and the associated release
+func (p *GiteaProvider) CreateRelease(ctx context.Context, name string, ref string, description string) error {
+	body := map[string]string{
+		"tag_name":         name,
+		"target_commitish": ref,
+		"name":             name,
+		"body":             description,
+	}
+	_, err := p.client.post(ctx, p.repoPath("/releases"), body, nil)
+	return err
+}
+
+type giteaCommit struct {
+	Sha    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name string    `json:"name"`
+			Date time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+	Parents []struct {
+		Sha string `json:"sha"`
+	} `json:"parents"`
+}
+
+func (p *GiteaProvider) projectName() string { return p.Owner + "/" + p.Repo }
+
+func (p *GiteaProvider) Links() model.Links {
+	if p.Host == "" {
+		return model.Links{}
+	}
+	base := "https://" + p.Host + "/" + p.Owner + "/" + p.Repo
+	return model.Links{Commit: base + "/commit/{ref}", Issue: base + "/issues/{number}"}
+}
+
+func (p *GiteaProvider) repoPath(path string) string {
+	return "/repos/" + url.PathEscape(p.Owner) + "/" + url.PathEscape(p.Repo) + path
+}
+
+func (p *GiteaProvider) listTags(ctx context.Context) ([]apiTag, error) {
+	tags := make([]apiTag, 0)
+	for page := 1; ; page++ {
+		var resp []struct {
+			Name   string `json:"name"`
+			Commit struct {
+				Sha string `json:"sha"`
+			} `json:"commit"`
+		}
+		_, err := p.client.get(ctx, p.repoPath("/tags"), giteaPage(page), &resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range resp {
+			tags = append(tags, apiTag{Name: t.Name, Commit: t.Commit.Sha})
+		}
+		if len(resp) < giteaPageSize {
+			return tags, nil
+		}
+	}
+}
+
+func (p *GiteaProvider) defaultBranch(ctx context.Context) (string, error) {
+	var resp struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	_, err := p.client.get(ctx, p.repoPath(""), nil, &resp)
+	return resp.DefaultBranch, err
+}
+
+func (p *GiteaProvider) readFile(ctx context.Context, ref string, path string) ([]byte, error) {
+	var content []byte
+	_, err := p.client.get(ctx, p.repoPath("/raw/"+path), url.Values{"ref": {ref}}, &content)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// isAncestor compares the other way round: no commit of the tag is missing from head
+func (p *GiteaProvider) isAncestor(ctx context.Context, tag apiTag, head string) (bool, error) {
+	commits, err := p.compare(ctx, head, tag.Commit)
+	if err != nil {
+		return false, err
+	}
+	return len(commits) == 0, nil
+}
+
+func (p *GiteaProvider) history(ctx context.Context, base string, head string) ([]model.ReleaseItem, error) {
+	var commits []giteaCommit
+
+	if base == "" {
+		for page := 1; ; page++ {
+			var resp []giteaCommit
+			query := giteaPage(page)
+			query.Set("sha", head)
+			query.Set("stat", "false")
+			_, err := p.client.get(ctx, p.repoPath("/commits"), query, &resp)
+			if err != nil {
+				return nil, err
+			}
+			commits = append(commits, resp...)
+			if len(resp) < giteaPageSize {
+				break
+			}
+		}
+	} else {
+		res, err := p.compare(ctx, base, head)
+		if err != nil {
+			return nil, err
+		}
+		// compare lists the oldest commit first
+		for i := len(res) - 1; i >= 0; i-- {
+			commits = append(commits, res[i])
+		}
+	}
+
+	result := make([]model.ReleaseItem, 0)
+	for _, c := range commits {
+		/* filter merge commit */
+		if len(c.Parents) < 2 {
+			result = append(result, model.NewReleaseItem(c.Sha, c.Commit.Author.Name, c.Commit.Author.Date, c.Commit.Message))
+		}
+	}
+	return result, nil
+}
+
+// compare lists the commits reachable from head but not from base
+func (p *GiteaProvider) compare(ctx context.Context, base string, head string) ([]giteaCommit, error) {
+	var resp struct {
+		Commits []giteaCommit `json:"commits"`
+	}
+	_, err := p.client.get(ctx, p.repoPath("/compare/"+url.PathEscape(base)+"..."+url.PathEscape(head)), nil, &resp)
+	return resp.Commits, err
+}
+
+func giteaPage(page int) url.Values {
+	return url.Values{
+		"page":  {strconv.Itoa(page)},
+		"limit": {strconv.Itoa(giteaPageSize)},
+	}
+}
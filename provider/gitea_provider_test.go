@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+)
+
+// fakeGitea serves a repository with the fakeHistory
+func fakeGitea(t *testing.T) *httptest.Server {
+	commits := func(ids []string) []interface{} {
+		res := make([]interface{}, 0)
+		for _, c := range ids {
+			res = append(res, map[string]interface{}{
+				"sha": c,
+				"commit": map[string]interface{}{
+					"message": fakeMessages[c],
+					"author":  map[string]string{"name": "tauf", "date": fakeDate.Format(time.RFC3339)},
+				},
+				"parents": []interface{}{},
+			})
+		}
+		return res
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/owner/repo", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]string{"default_branch": "main"})
+	})
+	mux.HandleFunc("/api/v1/repos/owner/repo/tags", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+		writeJSON(t, w, []interface{}{
+			map[string]interface{}{"name": "v2.0.0", "commit": map[string]string{"sha": "c5"}},
+			map[string]interface{}{"name": "v1.1.0", "commit": map[string]string{"sha": "c3"}},
+			map[string]interface{}{"name": "v1.0.0", "commit": map[string]string{"sha": "c1"}},
+		})
+	})
+	mux.HandleFunc("/api/v1/repos/owner/repo/raw/.nextver/config.yml", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/api/v1/repos/owner/repo/compare/", func(w http.ResponseWriter, r *http.Request) {
+		refs := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/repos/owner/repo/compare/"), "...")
+		if !assert.Len(t, refs, 2) {
+			http.Error(w, "base...head expected", http.StatusBadRequest)
+			return
+		}
+		writeJSON(t, w, map[string]interface{}{"commits": commits(oldestFirst(fakeCommitsBetween(refs[0], refs[1])))})
+	})
+	mux.HandleFunc("/api/v1/repos/owner/repo/commits", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, commits(fakeHistory[r.URL.Query().Get("sha")]))
+	})
+	mux.HandleFunc("/api/v1/repos/owner/repo/releases", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if !readJSON(t, w, r, &body) {
+			return
+		}
+		assert.Equal(t, "v1.2.0", body["tag_name"])
+		assert.Equal(t, "main", body["target_commitish"])
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(t, w, body)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newFakeGiteaProvider(t *testing.T, server *httptest.Server) *GiteaProvider {
+	p, err := NewGiteaProvider("", "owner", "repo", "secret", &GiteaProviderConfig{
+		ApiURL: server.URL + "/api/v1",
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestGiteaProvider_Links(t *testing.T) {
+	p, err := NewGiteaProvider("codeberg.org", "owner", "repo", "", &GiteaProviderConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://codeberg.org/owner/repo/commit/abc", p.Links().CommitURL("abc"))
+
+	// the web pages are unknown without host
+	p, err = NewGiteaProvider("", "owner", "repo", "", &GiteaProviderConfig{ApiURL: "https://gitea.example.com/api/v1"})
+	require.NoError(t, err)
+	assert.Equal(t, "", p.Links().CommitURL("abc"))
+}
+
+func TestGiteaProvider_NewGiteaProvider_noEndpoint(t *testing.T) {
+	_, err := NewGiteaProvider("", "owner", "repo", "", &GiteaProviderConfig{})
+	assert.Equal(t, &ConfigurationError{}, err)
+}
+
+func TestGiteaProvider_GetReleases(t *testing.T) {
+	server := fakeGitea(t)
+	defer server.Close()
+
+	actual, err := newFakeGiteaProvider(t, server).GetReleases(context.Background())
+	require.NoError(t, err)
+	require.Len(t, actual, 3)
+	assert.Equal(t, "v2.0.0", actual[0].CurrentVersion)
+	assert.Equal(t, "owner/repo", actual[0].Project)
+	assert.Equal(t, model.DefaultPattern, actual[0].VersionPattern)
+}
+
+func TestGiteaProvider_GetRelease_next(t *testing.T) {
+	server := fakeGitea(t)
+	defer server.Close()
+
+	actual, err := newFakeGiteaProvider(t, server).GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", actual.CurrentVersion)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "feature 4", actual.Changelog[0].Title)
+	assert.Equal(t, "v1.2.0", actual.MustNextVersion())
+}
+
+func TestGiteaProvider_GetRelease_named(t *testing.T) {
+	server := fakeGitea(t)
+	defer server.Close()
+
+	actual, err := newFakeGiteaProvider(t, server).GetRelease(context.Background(), "v2.0.0")
+	require.NoError(t, err)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "feature 5", actual.Changelog[0].Title)
+}
+
+func TestGiteaProvider_GetRelease_oldest(t *testing.T) {
+	server := fakeGitea(t)
+	defer server.Close()
+
+	actual, err := newFakeGiteaProvider(t, server).GetRelease(context.Background(), "v1.0.0")
+	require.NoError(t, err)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "Initial commit", actual.Changelog[0].Title)
+}
+
+func TestGiteaProvider_CreateRelease(t *testing.T) {
+	server := fakeGitea(t)
+	defer server.Close()
+
+	assert.NoError(t, newFakeGiteaProvider(t, server).CreateRelease(context.Background(), "v1.2.0", "main", "changelog"))
+}
+
+func TestGiteaProvider_GetReleases_cancelled(t *testing.T) {
+	server := fakeGitea(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := newFakeGiteaProvider(t, server).GetReleases(ctx)
+	assert.Error(t, err)
+}
@@ -5,13 +5,38 @@ package provider
 import (
 	"github.com/tauffredou/nextver/model"
 	"gopkg.in/yaml.v2"
-	"log"
 	"strings"
 	"time"
 )
 
+/*
+rateLimitQuery is embedded in the queries to follow the consumption of the rate limit
+
+graphql query:
+
+rateLimit {
+  cost
+  remaining
+  resetAt
+}
+
+*/
+type rateLimitQuery struct {
+	RateLimit struct {
+		Cost      int
+		Remaining int
+		ResetAt   time.Time
+	}
+}
+
+func (query *rateLimitQuery) getRateLimit() *rateLimitQuery { return query }
+
+// known tells if the server returned the rate limit, GHES may have no rate limit
+func (query *rateLimitQuery) known() bool { return !query.RateLimit.ResetAt.IsZero() }
+
 type PageInfo struct {
 	HasNextPage bool
+	EndCursor   string
 }
 
 /*
@@ -43,6 +68,7 @@ query ($owner: String!, $repo: String!) {
 
 */
 type tagsQuery struct {
+	rateLimitQuery
 	Repository struct {
 		Refs struct {
 			TagNodes []TagNode `graphql:"nodes"`
@@ -91,6 +117,7 @@ query($owner: String!, $repo: String!){
 
 */
 type defaultBranchQuery struct {
+	rateLimitQuery
 	Repository struct {
 		DefaultBranchRef struct {
 			Name string
@@ -98,6 +125,39 @@ type defaultBranchQuery struct {
 	} `graphql:"repository(owner: $owner, name: $name)"`
 }
 
+/*
+compareQuery tells how a head ref relates to a base ref.
+base is an ancestor of head when status is AHEAD or IDENTICAL
+
+graphql query:
+
+query ($owner: String!, $name: String!, $base: String!, $head: String!) {
+  repository(owner: $owner, name: $name) {
+    ref(qualifiedName: $base) {
+      compare(headRef: $head) {
+        status
+      }
+    }
+  }
+}
+
+*/
+type compareQuery struct {
+	rateLimitQuery
+	Repository struct {
+		Ref struct {
+			Compare struct {
+				Status string
+			} `graphql:"compare(headRef: $head)"`
+		} `graphql:"ref(qualifiedName: $base)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+func (query *compareQuery) isAncestor() bool {
+	status := query.Repository.Ref.Compare.Status
+	return status == "AHEAD" || status == "IDENTICAL"
+}
+
 /*
 graphql:
 
@@ -106,11 +166,12 @@ query (
   $owner: String!,
   $repo: String!,
   $release: String!,
+  $cursor: String,
 ) {
   repository(owner: $owner, name: $repo) {
     object(expression: $release) {
       ... on Commit {
-        history(first: 20,since: $since) {
+        history(first: 20,since: $since,after: $cursor) {
           pageInfo {
             endCursor
             startCursor
@@ -137,13 +198,14 @@ variables:
 }
 */
 type historyQuery struct {
+	rateLimitQuery
 	Repository struct {
 		Ref struct {
 			Commit struct {
 				History struct {
 					PageInfo PageInfo
 					Nodes    []CommitNode
-				} `graphql:"history(first: $itemsCount,since: $since)"`
+				} `graphql:"history(first: $itemsCount,since: $since,after: $cursor)"`
 			} `graphql:"... on Commit"`
 		} `graphql:"object(expression: $release)"`
 	} `graphql:"repository(owner: $owner, name: $name)"`
@@ -165,6 +227,101 @@ type Author struct {
 	Date  time.Time
 }
 
+/*
+pullRequestHistoryQuery is the history with the merged pull request of each commit
+
+graphql:
+
+query ($owner: String!, $repo: String!, $release: String!, $since: GitTimestamp!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    object(expression: $release) {
+      ... on Commit {
+        history(first: 50, since: $since, after: $cursor) {
+          pageInfo { hasNextPage endCursor }
+          nodes {
+            oid
+            message
+            author { name email date }
+            associatedPullRequests(first: 1) {
+              nodes {
+                number
+                title
+                body
+                url
+                mergedAt
+                author { login }
+                labels(first: 20) {
+                  nodes { name }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+
+*/
+type pullRequestHistoryQuery struct {
+	rateLimitQuery
+	Repository struct {
+		Ref struct {
+			Commit struct {
+				History struct {
+					PageInfo PageInfo
+					Nodes    []PullRequestCommitNode
+				} `graphql:"history(first: $itemsCount,since: $since,after: $cursor)"`
+			} `graphql:"... on Commit"`
+		} `graphql:"object(expression: $release)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+func (query *pullRequestHistoryQuery) getCommits() []PullRequestCommitNode {
+	return query.Repository.Ref.Commit.History.Nodes
+}
+
+type PullRequestCommitNode struct {
+	CommitNode
+	AssociatedPullRequests struct {
+		Nodes []PullRequestNode
+	} `graphql:"associatedPullRequests(first: 1)"`
+}
+
+// getPullRequest returns the merged pull request of the commit, nil for a direct push
+func (node *PullRequestCommitNode) getPullRequest() *PullRequestNode {
+	for i := range node.AssociatedPullRequests.Nodes {
+		if pr := &node.AssociatedPullRequests.Nodes[i]; pr.MergedAt != nil {
+			return pr
+		}
+	}
+	return nil
+}
+
+type PullRequestNode struct {
+	Number   int
+	Title    string
+	Body     string
+	URL      string `graphql:"url"`
+	MergedAt *time.Time
+	Author   struct {
+		Login string
+	}
+	Labels struct {
+		Nodes []struct {
+			Name string
+		}
+	} `graphql:"labels(first: 20)"`
+}
+
+func (pr *PullRequestNode) getLabels() []string {
+	labels := make([]string, 0, len(pr.Labels.Nodes))
+	for _, l := range pr.Labels.Nodes {
+		labels = append(labels, l.Name)
+	}
+	return labels
+}
+
 /*
 Query config file content without checkout
 graphql:
@@ -181,6 +338,7 @@ query ($owner: String!, $repo: String!, $file: String!) {
 
 */
 type configFileQuery struct {
+	rateLimitQuery
 	Repository struct {
 		Content struct {
 			Blob struct {
@@ -198,11 +356,11 @@ func (query *configFileQuery) getBytes() []byte {
 	return []byte(query.Repository.Content.Blob.Text)
 }
 
-func (query *configFileQuery) mustGetConfig() *model.Config {
+func (query *configFileQuery) getConfig() (*model.Config, error) {
 	var c model.Config
 	err := yaml.Unmarshal(query.getBytes(), &c)
 	if err != nil {
-		log.Fatal(err)
+		return nil, &ConfigurationError{Reason: err.Error()}
 	}
-	return &c
+	return &c, nil
 }
@@ -1,15 +1,20 @@
 package provider
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/shurcooL/githubv4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGithubProvider_getFirstTag(t *testing.T) {
@@ -17,7 +22,8 @@ func TestGithubProvider_getFirstTag(t *testing.T) {
 
 	p := &GithubProvider{client: mockGithubClient(resp)}
 
-	q := p.mustQueryReleases()
+	q, err := p.queryReleases(context.Background())
+	require.NoError(t, err)
 	tag := q.GetTags()[0]
 	assert.Equal(t, "v1.1.0", tag.getId())
 	assert.Equal(t, "a3240571ac4bbe857a0cfad3b988942838e758d1", tag.getCommitId())
@@ -57,7 +63,8 @@ func TestGithubProvider_GetReleases(t *testing.T) {
 
 	p := &GithubProvider{client: mockGithubClient(resp)}
 
-	_ = p.mustQueryReleases()
+	_, err := p.queryReleases(context.Background())
+	assert.NoError(t, err)
 }
 
 func TestGithubProvider_getReleaseBoundary_empty(t *testing.T) {
@@ -73,7 +80,7 @@ func TestGithubProvider_getReleaseBoundary_empty(t *testing.T) {
 
 	p := &GithubProvider{client: mockGithubClient(resp)}
 
-	first, last, err := p.getReleaseBoundary("v1.1.0")
+	first, last, err := p.getReleaseBoundary(context.Background(), "v1.1.0")
 	assert.NoError(t, err)
 	assert.Equal(t, "", first)
 	assert.Equal(t, "", last)
@@ -102,24 +109,123 @@ func TestGithubProvider_getReleaseBoundary_one(t *testing.T) {
 
 	p := &GithubProvider{client: mockGithubClient(resp)}
 
-	first, last, err := p.getReleaseBoundary("v1.1.0")
+	first, last, err := p.getReleaseBoundary(context.Background(), "v1.1.0")
 	assert.NoError(t, err)
 	assert.Equal(t, "a3240571ac4bbe857a0cfad3b988942838e758d1", first)
 	assert.Equal(t, "", last)
 }
 
 func TestGithubProvider_getReleaseBoundary(t *testing.T) {
-	resp := mockResponseFile("../fixtures/github/releases.response.json")
+	resp := mockQueries(map[string]string{
+		"refs(":    mustReadFile("../fixtures/github/releases.response.json"),
+		"compare(": compareResponse("AHEAD"),
+	})
 
 	p := &GithubProvider{client: mockGithubClient(resp)}
 
-	first, last, err := p.getReleaseBoundary("v1.1.0")
+	first, last, err := p.getReleaseBoundary(context.Background(), "v1.1.0")
 	assert.NoError(t, err)
 	assert.Equal(t, "a3240571ac4bbe857a0cfad3b988942838e758d1", first)
 	assert.Equal(t, "1c23cc36d1383b82198af6ee04fe44b820b6a550", last)
 }
 
-func TestGithubProvider_MustGetPattern(t *testing.T) {
+func TestGithubProvider_getReleaseBoundary_notAncestor(t *testing.T) {
+	resp := mockQueries(map[string]string{
+		"refs(":    mustReadFile("../fixtures/github/releases.response.json"),
+		"compare(": compareResponse("DIVERGED"),
+	})
+
+	p := &GithubProvider{client: mockGithubClient(resp)}
+
+	first, last, err := p.getReleaseBoundary(context.Background(), "v1.1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "a3240571ac4bbe857a0cfad3b988942838e758d1", first)
+	assert.Equal(t, "", last)
+}
+
+func TestGithubProvider_getLastReleaseTag_ancestorOnly(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := ioutil.ReadAll(req.Body)
+		switch {
+		case strings.Contains(string(body), "compare("):
+			// only v1.0.1 is reachable from the branch
+			if strings.Contains(string(body), "refs/tags/v1.0.1") {
+				mustWrite(w, compareResponse("AHEAD"))
+			} else {
+				mustWrite(w, compareResponse("DIVERGED"))
+			}
+		default:
+			mustWrite(w, mustReadFile("../fixtures/github/releases.response.json"))
+		}
+	})
+
+	p := &GithubProvider{
+		client: mockGithubClient(mux),
+		config: &GithubProviderConfig{Branch: "release/1.0.x", Pattern: "vSEMVER"},
+	}
+
+	tag, err := p.getLastReleaseTag(context.Background(), "release/1.0.x")
+	require.NoError(t, err)
+	if assert.NotNil(t, tag) {
+		assert.Equal(t, "v1.0.1", tag.getId())
+	}
+}
+
+// maintenanceBranchGithub serves the releases fixture where only v1.0.1 is reachable from the branch,
+// the body of the history query is kept in historyQuery
+func maintenanceBranchGithub(historyQuery *string) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := ioutil.ReadAll(req.Body)
+		switch {
+		case strings.Contains(string(body), "compare("):
+			if strings.Contains(string(body), "refs/tags/v1.0.1") {
+				mustWrite(w, compareResponse("AHEAD"))
+			} else {
+				mustWrite(w, compareResponse("DIVERGED"))
+			}
+		case strings.Contains(string(body), "history("):
+			*historyQuery = string(body)
+			mustWrite(w, mustReadFile("../fixtures/github/history.response.json"))
+		default:
+			mustWrite(w, mustReadFile("../fixtures/github/releases.response.json"))
+		}
+	})
+	return mux
+}
+
+func TestGithubProvider_GetNextRelease_branch(t *testing.T) {
+	var historyQuery string
+	p := &GithubProvider{
+		client: mockGithubClient(maintenanceBranchGithub(&historyQuery)),
+		config: &GithubProviderConfig{Branch: "release/1.0.x", Pattern: "vSEMVER"},
+	}
+
+	r, err := p.GetNextRelease(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.1", r.CurrentVersion)
+	// the history is the one of the maintenance branch, not of HEAD
+	assert.Contains(t, historyQuery, `"release":"release/1.0.x"`)
+}
+
+func TestGithubProvider_GetRelease_next(t *testing.T) {
+	var historyQuery string
+	p := &GithubProvider{
+		client: mockGithubClient(maintenanceBranchGithub(&historyQuery)),
+		config: &GithubProviderConfig{Branch: "release/1.0.x", Pattern: "vSEMVER"},
+	}
+
+	// the newest tag v1.1.0 is on another branch, the release starts at the tag reachable from the branch
+	r, err := p.GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.1", r.CurrentVersion)
+	assert.Contains(t, historyQuery, `"release":"release/1.0.x"`)
+}
+
+func TestGithubProvider_GetPattern(t *testing.T) {
 	resp := mockResponse(`{
   "data": {
     "repository": {
@@ -136,19 +242,22 @@ func TestGithubProvider_MustGetPattern(t *testing.T) {
 		},
 	}
 
-	actual := p.MustGetPattern()
+	actual, err := p.GetPattern(context.Background())
+	require.NoError(t, err)
 	expected := "v-SEMVER"
 	assert.Equal(t, expected, actual)
 }
 
-func TestGithubProvider_MustGetPattern_definedInProvider(t *testing.T) {
+func TestGithubProvider_GetPattern_definedInProvider(t *testing.T) {
 	p := &GithubProvider{
 		pattern: "test2",
 	}
 
-	assert.Equal(t, "test2", p.MustGetPattern())
+	actual, err := p.GetPattern(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "test2", actual)
 }
-func TestGithubProvider_MustGetPattern_definedInConfiguration(t *testing.T) {
+func TestGithubProvider_GetPattern_definedInConfiguration(t *testing.T) {
 
 	p := &GithubProvider{
 		config: &GithubProviderConfig{
@@ -156,7 +265,9 @@ func TestGithubProvider_MustGetPattern_definedInConfiguration(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, "test3", p.MustGetPattern())
+	actual, err := p.GetPattern(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "test3", actual)
 }
 
 func TestGithubProvider_historyQuery(t *testing.T) {
@@ -165,16 +276,21 @@ func TestGithubProvider_historyQuery(t *testing.T) {
 		client: mockGithubClient(resp),
 	}
 
-	actual := p.mustGetHistory(nil)
+	actual, err := p.queryHistory(context.Background(), nil)
+	require.NoError(t, err)
 	assert.Len(t, actual.getCommits(), 5)
 }
 
 func mockResponseFile(f string) *http.ServeMux {
+	return mockResponse(mustReadFile(f))
+}
+
+func mustReadFile(f string) string {
 	content, err := ioutil.ReadFile(f)
 	if err != nil {
 		log.Fatal(err)
 	}
-	return mockResponse(string(content))
+	return string(content)
 }
 
 /* helpers */
@@ -192,6 +308,27 @@ func mockResponse(resp string) *http.ServeMux {
 	return mux
 }
 
+// mockQueries answers with the response whose key is contained in the query
+func mockQueries(responses map[string]string) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := ioutil.ReadAll(req.Body)
+		for k, resp := range responses {
+			if strings.Contains(string(body), k) {
+				mustWrite(w, resp)
+				return
+			}
+		}
+		http.Error(w, "unexpected query", http.StatusBadRequest)
+	})
+	return mux
+}
+
+func compareResponse(status string) string {
+	return `{"data": {"repository": {"ref": {"compare": {"status": "` + status + `"}}}}}`
+}
+
 func mustWrite(w io.Writer, s string) {
 	_, err := io.WriteString(w, s)
 	if err != nil {
@@ -208,3 +345,153 @@ func (l localRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 	l.handler.ServeHTTP(w, req)
 	return w.Result(), nil
 }
+
+func TestGithubProvider_GetReleases_deadline(t *testing.T) {
+	// the handler hangs until the end of the test
+	done := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-done:
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer close(done)
+
+	p, err := NewGithubProvider("owner", "repo", "token", &GithubProviderConfig{ApiURL: server.URL + "/api/v3", Pattern: "vSEMVER"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = p.GetReleases(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
+
+func TestGithubProvider_getHistory_pullRequests(t *testing.T) {
+	p := newPullRequestsProvider(`{"data": {"repository": {"content": null}}}`)
+
+	actual, err := p.getHistory(context.Background(), "HEAD", "236be09573257deb629ccc650245e5c616221a7f")
+	require.NoError(t, err)
+	require.Len(t, actual, 3)
+
+	assert.Equal(t, "Export the changelog", actual[0].Title)
+	assert.Equal(t, "feat", actual[0].Kind)
+	assert.Equal(t, "tauffredou", actual[0].Author)
+	require.NotNil(t, actual[0].PullRequest)
+	assert.Equal(t, 3, actual[0].PullRequest.Number)
+	assert.Equal(t, "https://github.com/owner/repo/pull/3", actual[0].PullRequest.URL)
+	assert.Equal(t, []string{"enhancement"}, actual[0].PullRequest.Labels)
+
+	assert.Equal(t, "typo in readme", actual[1].Title)
+	assert.Nil(t, actual[1].PullRequest)
+
+	assert.Equal(t, "Rework the api", actual[2].Title)
+	assert.Equal(t, "MAJOR", actual[2].LevelName())
+}
+
+func TestGithubProvider_getHistory_pullRequestsLabels(t *testing.T) {
+	p := newPullRequestsProvider(`{"data": {"repository": {"content": {"text": "labels:\n  enhancement:\n    level: PATCH\n    section: Improvements\n  breaking-change:\n    section: Breaking\n"}}}}`)
+
+	actual, err := p.getHistory(context.Background(), "HEAD", "236be09573257deb629ccc650245e5c616221a7f")
+	require.NoError(t, err)
+	require.Len(t, actual, 3)
+	assert.Equal(t, "PATCH", actual[0].LevelName())
+	assert.Equal(t, "Improvements", actual[0].Section)
+	// the default labels are replaced
+	assert.Equal(t, "", actual[2].LevelName())
+	assert.Equal(t, "Breaking", actual[2].Section)
+}
+
+func TestGithubProvider_getHistory_pullRequestsInvalidLabels(t *testing.T) {
+	p := newPullRequestsProvider(`{"data": {"repository": {"content": {"text": "labels:\n  bug:\n    level: HUGE\n"}}}}`)
+
+	_, err := p.getHistory(context.Background(), "HEAD", "")
+	assert.True(t, IsConfigurationError(err))
+}
+
+// newPullRequestsProvider serves the pull requests fixture and the given configuration file
+func newPullRequestsProvider(configFile string) *GithubProvider {
+	resp := mockQueries(map[string]string{
+		"associatedPullRequests(first: 1){nodes{number,title,body,url,mergedAt,author{login},labels(first: 20)": mustReadFile("../fixtures/github/pullRequests.response.json"),
+		"content:object": configFile,
+	})
+	return &GithubProvider{
+		client: mockGithubClient(resp),
+		config: &GithubProviderConfig{PullRequests: true, Branch: "master"},
+	}
+}
+
+// pagedHistory answers the first page of the history, then the second page once asked for the cursor
+func pagedHistory(firstPage, secondPage string) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := ioutil.ReadAll(req.Body)
+		switch {
+		case strings.Contains(string(body), "content:object"):
+			mustWrite(w, `{"data": {"repository": {"content": null}}}`)
+		case strings.Contains(string(body), `"cursor":null`):
+			mustWrite(w, firstPage)
+		case strings.Contains(string(body), `"cursor":"page2"`):
+			mustWrite(w, secondPage)
+		default:
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+		}
+	})
+	return mux
+}
+
+func historyPage(hasNextPage bool, nodes ...string) string {
+	return fmt.Sprintf(`{"data": {"repository": {"object": {"history": {"pageInfo": {"hasNextPage": %t, "endCursor": "page2"}, "nodes": [%s]}}}}}`,
+		hasNextPage, strings.Join(nodes, ","))
+}
+
+func commitNode(oid, message string) string {
+	return fmt.Sprintf(`{"oid": %q, "message": %q, "author": {"name": "tauf", "date": "2019-06-25T12:35:41Z"}}`, oid, message)
+}
+
+// pullRequestNode is a commit of the pull request, or of no pull request when 0
+func pullRequestNode(oid, message string, pullRequest int) string {
+	pullRequests := ""
+	if pullRequest > 0 {
+		pullRequests = fmt.Sprintf(`{"number": %d, "title": "pull request %d", "url": "https://github.com/owner/repo/pull/%d", "mergedAt": "2019-06-25T12:35:41Z", "author": {"login": "tauf"}, "labels": {"nodes": []}}`,
+			pullRequest, pullRequest, pullRequest)
+	}
+	return fmt.Sprintf(`{"oid": %q, "message": %q, "author": {"name": "tauf", "date": "2019-06-25T12:35:41Z"}, "associatedPullRequests": {"nodes": [%s]}}`,
+		oid, message, pullRequests)
+}
+
+func TestGithubProvider_getHistory_pages(t *testing.T) {
+	p := &GithubProvider{
+		client: mockGithubClient(pagedHistory(
+			historyPage(true, commitNode("c4", "fix: fourth"), commitNode("c3", "feat: third")),
+			historyPage(true, commitNode("c2", "fix: second"), commitNode("c1", "feat: first")),
+		)),
+	}
+
+	actual, err := p.getHistory(context.Background(), "HEAD", "c1")
+	require.NoError(t, err)
+	require.Len(t, actual, 3)
+	assert.Equal(t, "fourth", actual[0].Title)
+	assert.Equal(t, "second", actual[2].Title)
+}
+
+func TestGithubProvider_getHistory_pullRequestPages(t *testing.T) {
+	p := &GithubProvider{
+		client: mockGithubClient(pagedHistory(
+			historyPage(true, pullRequestNode("c4", "fix: fourth", 2), pullRequestNode("c3", "feat: third", 1)),
+			historyPage(false, pullRequestNode("c2", "fix: second", 1), pullRequestNode("c1", "feat: first", 0)),
+		)),
+		config: &GithubProviderConfig{PullRequests: true, Branch: "master"},
+	}
+
+	actual, err := p.getHistory(context.Background(), "HEAD", "")
+	require.NoError(t, err)
+	require.Len(t, actual, 3, "the pull request 1 is listed once across the pages")
+	assert.Equal(t, 2, actual[0].PullRequest.Number)
+	assert.Equal(t, 1, actual[1].PullRequest.Number)
+	assert.Equal(t, "first", actual[2].Title)
+}
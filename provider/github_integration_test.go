@@ -3,6 +3,7 @@
 package provider
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"os"
@@ -32,7 +33,8 @@ func TestIntegration_GithubProvider_GetNextRelease(t *testing.T) {
 	// using a public repository to test integration
 	p, err := NewGithubProvider("tauffredou", "test-semver", getToken(), intConfig)
 	assert.NoError(t, err)
-	r := p.GetNextRelease()
+	r, err := p.GetNextRelease(context.Background())
+	require.NoError(t, err)
 	assert.Equal(t, "v1.1.0", r.CurrentVersion)
 	assert.Equal(t, "v1.2.0", r.MustNextVersion())
 	assert.Len(t, r.Changelog, 2)
@@ -44,7 +46,7 @@ func TestIntegration_GithubProvider_GetRelease(t *testing.T) {
 	// using a public repository to test integration
 	p, err := NewGithubProvider("tauffredou", "test-semver", getToken(), intConfig)
 	assert.NoError(t, err)
-	r, err := p.GetRelease("v1.1.0")
+	r, err := p.GetRelease(context.Background(), "v1.1.0")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, r)
@@ -59,7 +61,7 @@ func TestIntegration_GithubProvider_GetReleases(t *testing.T) {
 	p, err := NewGithubProvider("tauffredou", "test-semver", getToken(), intConfig)
 	assert.NoError(t, err)
 
-	actual, err := p.GetReleases()
+	actual, err := p.GetReleases(context.Background())
 	require.NoError(t, err)
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "v1.1.0", actual[0].CurrentVersion)
@@ -1,14 +1,15 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"github.com/shurcooL/githubv4"
 	log "github.com/sirupsen/logrus"
 	"github.com/tauffredou/nextver/model"
-	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"regexp"
 	"strings"
@@ -28,12 +29,31 @@ type GithubProvider struct {
 	Repo          string
 	pattern       string
 	Branch        string
+	// rateLimit is the state of the rate limit after the last query
+	rateLimit rateLimitQuery
+	// configFile is the configuration file of the repository, empty when there is none
+	configFile *model.Config
 }
 
 type GithubProviderConfig struct {
 	Branch    string
 	Pattern   string
 	BeforeRef string
+	// ApiURL is the endpoint of a Github Enterprise Server, api.github.com is used when empty
+	ApiURL string
+	// Retry configures the retries of the failed queries, DefaultRetryConfig is used when nil
+	Retry *RetryConfig
+	// Cache stores the responses, they are only memoized for the life of the provider when nil
+	Cache *Cache
+	// PullRequests builds the changelog from the merged pull requests instead of the commits
+	PullRequests bool
+}
+
+func (c *GithubProviderConfig) retryConfig() RetryConfig {
+	if c == nil || c.Retry == nil {
+		return DefaultRetryConfig
+	}
+	return *c.Retry
 }
 
 func NewGithubProvider(owner string, repo string, token string, config *GithubProviderConfig) (*GithubProvider, error) {
@@ -41,53 +61,121 @@ func NewGithubProvider(owner string, repo string, token string, config *GithubPr
 		return nil, &ConfigurationError{}
 	}
 
-	log.WithField("token", obfuscateToken(token)).Debug("Init github provider")
+	log.WithField("token", obfuscateToken(token)).WithField("url", config.ApiURL).Debug("Init github provider")
 
 	src := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
-	httpClient := oauth2.NewClient(context.Background(), src)
+	cache := config.Cache
+	if cache == nil {
+		cache = NewCache("", 0)
+	}
+	// the cache is keyed by the token, it sits between the authentication and the retries
+	httpClient := &http.Client{Transport: &oauth2.Transport{
+		Source: src,
+		Base:   cache.Transport(newRetryTransport(nil, config.retryConfig())),
+	}}
+
+	client := githubv4.NewClient(httpClient)
+	if config.ApiURL != "" {
+		client = githubv4.NewEnterpriseClient(GithubGraphqlURL(config.ApiURL), httpClient)
+	}
 
 	return &GithubProvider{
 		Owner:  owner,
 		Repo:   repo,
-		client: githubv4.NewClient(httpClient),
+		client: client,
 		config: config,
 	}, nil
 }
 
-type ConfigurationError struct{}
+// GithubGraphqlURL finds the graphql endpoint from the url of a Github instance.
+// It accepts the server url (https://github.example.com), the REST API url (https://github.example.com/api/v3)
+// as found in GITHUB_API_URL, or the graphql endpoint itself
+func GithubGraphqlURL(u string) string {
+	u = strings.TrimRight(u, "/")
+	switch {
+	case strings.HasSuffix(u, "/graphql"):
+		return u
+	case strings.HasSuffix(u, "/api/v3"):
+		return strings.TrimSuffix(u, "/v3") + "/graphql"
+	case strings.HasPrefix(u, "https://api.github.com"), strings.HasPrefix(u, "http://api.github.com"):
+		return u + "/graphql"
+	default:
+		return u + "/api/graphql"
+	}
+}
 
-func (e *ConfigurationError) Error() string {
-	return "Invalid configuration"
+// githubWebURL finds the url of the Github instance from its API url, github.com when empty
+func githubWebURL(apiURL string) string {
+	u := strings.TrimRight(apiURL, "/")
+	if u == "" || strings.HasPrefix(u, "https://api.github.com") || strings.HasPrefix(u, "http://api.github.com") {
+		return "https://github.com"
+	}
+	u = strings.TrimSuffix(u, "/graphql")
+	u = strings.TrimSuffix(u, "/v3")
+	return strings.TrimSuffix(u, "/api")
 }
 
-func (p *GithubProvider) GetNextRelease() *model.Release {
+func (p *GithubProvider) Links() model.Links {
+	var apiURL string
+	if p.config != nil {
+		apiURL = p.config.ApiURL
+	}
+	web := githubWebURL(apiURL)
+	base := web + "/" + p.Owner + "/" + p.Repo
+	return model.Links{Commit: base + "/commit/{ref}", Issue: base + "/issues/{number}", Avatar: web + "/{login}.png"}
+}
 
+// GetNextRelease returns the last release reachable from the target branch with the changes since then
+func (p *GithubProvider) GetNextRelease(ctx context.Context) (*model.Release, error) {
+	pattern, err := p.GetPattern(ctx)
+	if err != nil {
+		return nil, err
+	}
 	release := model.Release{
 		Project:        fmt.Sprintf("%s/%s", p.Owner, p.Repo),
-		VersionPattern: p.MustGetPattern(),
+		VersionPattern: pattern,
 	}
 
-	previousTag := p.getLastReleaseTag()
+	branch, err := p.getBranch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	previousTag, err := p.getLastReleaseTag(ctx, branch)
+	if err != nil {
+		return nil, err
+	}
 	if previousTag != nil {
 		release.CurrentVersion = previousTag.getId()
 		release.Ref = previousTag.getCommitId()
-		release.Changelog = p.getHistory("HEAD", previousTag.getCommitId())
 	} else {
 		release.CurrentVersion = model.FirstVersion
 		release.Ref = FirstCommit
-		release.Changelog = p.getHistory("HEAD", FirstCommit)
 	}
 
-	return &release
+	release.Changelog, err = p.getHistory(ctx, branch, release.Ref)
+	if err != nil {
+		return nil, err
+	}
+	return &release, nil
 }
 
 //GetReleases returns the list of tags matching the release pattern
-func (p *GithubProvider) GetReleases() ([]model.Release, error) {
+func (p *GithubProvider) GetReleases(ctx context.Context) ([]model.Release, error) {
 	log.Debug("Getting release")
 
-	query := p.mustQueryReleases()
+	query, err := p.queryReleases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.GetPattern(ctx); err != nil {
+		return nil, err
+	}
+	re, err := p.versionRegexp(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	r := make([]model.Release, 0)
 	tags := query.Repository.Refs.TagNodes
@@ -95,49 +183,100 @@ func (p *GithubProvider) GetReleases() ([]model.Release, error) {
 	// reverse order
 	for i := len(tags) - 1; i >= 0; i-- {
 		v := tags[i]
-		if p.tagFilter(v.Target.TagInfo) {
-			tag := p.tagMapper(v.Target.TagInfo, nil)
-			r = append(r, tag)
+		if re.MatchString(v.Target.TagInfo.getId()) {
+			r = append(r, p.tagMapper(v.Target.TagInfo, nil))
 		}
 	}
 
 	return r, nil
 }
 
-func (p *GithubProvider) GetRelease(name string) (*model.Release, error) {
+// GetRelease returns the changes of the named release, or the next release of the target branch when name is empty
+func (p *GithubProvider) GetRelease(ctx context.Context, name string) (*model.Release, error) {
+	if name == "" {
+		return p.GetNextRelease(ctx)
+	}
 
-	from, to, _ := p.getReleaseBoundary(name)
+	from, to, err := p.getReleaseBoundary(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if from == "" {
+		return nil, &NotFoundError{Resource: "release " + name}
+	}
+
+	pattern, err := p.GetPattern(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changelog, err := p.getHistory(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
 
 	r := model.Release{
 		CurrentVersion: name,
-		Changelog:      p.getHistory(from, to),
-		VersionPattern: p.MustGetPattern(),
+		Changelog:      changelog,
+		VersionPattern: pattern,
 	}
 	return &r, nil
 }
 
-//MustGetPattern tries to fetch the config file
-func (p *GithubProvider) MustGetPattern() string {
+// GetPattern tries to fetch the config file
+func (p *GithubProvider) GetPattern(ctx context.Context) (string, error) {
 	log.Debug("get pattern")
 	if p.pattern != "" {
-		return p.pattern
+		return p.pattern, nil
 	}
 
 	if p.config.Pattern != "" {
 		p.pattern = p.config.Pattern
-		return p.pattern
+		return p.pattern, nil
 	}
 
-	query := p.mustQueryConfigFile()
+	c, err := p.readConfigFile(ctx)
+	if err != nil {
+		return "", err
+	}
 
-	if query.hasFile() {
-		p.pattern = query.mustGetConfig().Pattern
+	if c.Pattern != "" {
+		p.pattern = c.Pattern
 		log.WithField("pattern", p.pattern).Debug("got pattern from github")
 	} else {
 		p.pattern = model.DefaultPattern
 		log.WithField("pattern", p.pattern).Debug("got pattern from default")
 	}
-	return p.pattern
+	return p.pattern, nil
+}
+
+// Config reads the configuration file of the target branch
+func (p *GithubProvider) Config(ctx context.Context) (*model.Config, error) {
+	return p.readConfigFile(ctx)
+}
+
+// readConfigFile reads the configuration file of the target branch once, the configuration is empty without file
+func (p *GithubProvider) readConfigFile(ctx context.Context) (*model.Config, error) {
+	if p.configFile != nil {
+		return p.configFile, nil
+	}
+
+	query, err := p.queryConfigFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &model.Config{}
+	if query.hasFile() {
+		c, err = query.getConfig()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Validate(); err != nil {
+			return nil, &ConfigurationError{Reason: err.Error()}
+		}
+	}
+	p.configFile = c
+	return c, nil
 }
 
 // readHubToken read token form hub config when available
@@ -164,47 +303,121 @@ func ReadHubToken(f string) (string, error) {
 
 }
 
-func (p *GithubProvider) getLastReleaseTag() *TagNode {
-	tagsQuery := p.mustQueryReleases()
+// getLastReleaseTag returns the most recent release tag reachable from the branch
+func (p *GithubProvider) getLastReleaseTag(ctx context.Context, branch string) (*TagNode, error) {
+	tagsQuery, err := p.queryReleases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	re, err := p.versionRegexp(ctx)
+	if err != nil {
+		return nil, err
+	}
 	tags := tagsQuery.GetTags()
 
 	// reverse order
 	for i := len(tags) - 1; i >= 0; i-- {
 		tag := tags[i]
+		if !re.MatchString(tag.getId()) {
+			continue
+		}
 
-		if p.GetVersionRegexp().MatchString(tag.getId()) {
-			return &tag
+		ok, err := p.isAncestor(ctx, tag.Name, branch)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &tag, nil
 		}
 	}
 
-	return nil
+	return nil, nil
 }
 
-func (p *GithubProvider) getHistory(fromRef string, toRef string) []model.ReleaseItem {
+// getHistory lists the commits from fromRef back to toRef, excluded, page by page
+func (p *GithubProvider) getHistory(ctx context.Context, fromRef string, toRef string) ([]model.ReleaseItem, error) {
+	if p.config != nil && p.config.PullRequests {
+		return p.getPullRequestHistory(ctx, fromRef, toRef)
+	}
 
-	variables := p.defaultVariables()
+	result := make([]model.ReleaseItem, 0)
+	variables := p.historyVariables(fromRef)
+	for {
+		query, err := p.queryHistory(ctx, variables)
+		if err != nil {
+			return nil, err
+		}
 
-	variables["release"] = githubv4.String(fromRef)
-	variables["itemsCount"] = githubv4.Int(50)
-	ts, _ := time.Parse(time.RFC3339, "1900-01-01T00:00:00Z")
-	variables["since"] = githubv4.GitTimestamp{Time: ts}
+		for _, c := range query.getCommits() {
+			if c.Oid == toRef {
+				return result, nil
+			}
+			result = append(result, model.NewReleaseItem(c.Oid, c.Author.Name, c.Author.Date, c.Message))
+		}
 
-	query := p.mustGetHistory(variables)
+		page := query.Repository.Ref.Commit.History.PageInfo
+		if !page.HasNextPage {
+			return result, nil
+		}
+		variables["cursor"] = githubv4.NewString(githubv4.String(page.EndCursor))
+	}
+}
+
+// getPullRequestHistory lists the merged pull requests between the refs, once each.
+// The commits pushed without pull request are kept
+func (p *GithubProvider) getPullRequestHistory(ctx context.Context, fromRef string, toRef string) ([]model.ReleaseItem, error) {
+	c, err := p.readConfigFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mapping := c.LabelMapping()
 
 	result := make([]model.ReleaseItem, 0)
+	seen := make(map[int]bool)
+	variables := p.historyVariables(fromRef)
+	for {
+		var query pullRequestHistoryQuery
+		if err := p.query(ctx, &query, variables); err != nil {
+			return nil, err
+		}
 
-	commits := query.getCommits()
+		for _, c := range query.getCommits() {
+			if c.Oid == toRef {
+				return result, nil
+			}
+			pr := c.getPullRequest()
+			if pr == nil {
+				result = append(result, model.NewReleaseItem(c.Oid, c.Author.Name, c.Author.Date, c.Message))
+				continue
+			}
+			if seen[pr.Number] {
+				continue
+			}
+			seen[pr.Number] = true
+			result = append(result, model.NewPullRequestItem(c.Oid, pr.Author.Login, *pr.MergedAt, pr.Title, pr.Body, model.PullRequest{
+				Number: pr.Number,
+				URL:    pr.URL,
+				Labels: pr.getLabels(),
+			}, mapping))
+		}
 
-	for _, c := range commits {
-		if c.Oid == toRef {
-			break
+		page := query.Repository.Ref.Commit.History.PageInfo
+		if !page.HasNextPage {
+			return result, nil
 		}
-		ri := model.NewReleaseItem(c.Oid, c.Author.Name, c.Author.Date, c.Message)
-		result = append(result, ri)
+		variables["cursor"] = githubv4.NewString(githubv4.String(page.EndCursor))
 	}
+}
 
-	return result
-
+// historyVariables are the variables of the first page of the history of fromRef
+func (p *GithubProvider) historyVariables(fromRef string) map[string]interface{} {
+	variables := p.defaultVariables()
+	variables["release"] = githubv4.String(fromRef)
+	variables["itemsCount"] = githubv4.Int(50)
+	variables["cursor"] = (*githubv4.String)(nil)
+	ts, _ := time.Parse(time.RFC3339, "1900-01-01T00:00:00Z")
+	variables["since"] = githubv4.GitTimestamp{Time: ts}
+	return variables
 }
 
 func (p *GithubProvider) defaultVariables() map[string]interface{} {
@@ -227,27 +440,51 @@ func obfuscateToken(token string) string {
 	return sb.String()
 }
 
-func (p *GithubProvider) mustGetHistory(variables map[string]interface{}) *historyQuery {
-	var query historyQuery
+// query runs a graphql query, the errors are typed when possible.
+// When the previous query left too few points of rate limit, the query waits for the reset
+func (p *GithubProvider) query(ctx context.Context, q interface{}, variables map[string]interface{}) error {
+	if err := p.waitRateLimit(ctx); err != nil {
+		return err
+	}
 
-	err := p.client.Query(context.Background(), &query, variables)
+	err := classifyGithubError(p.client.Query(ctx, q, variables))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	return &query
+
+	if v, ok := q.(interface{ getRateLimit() *rateLimitQuery }); ok && v.getRateLimit().known() {
+		p.rateLimit = *v.getRateLimit()
+		log.WithField("cost", p.rateLimit.RateLimit.Cost).
+			WithField("remaining", p.rateLimit.RateLimit.Remaining).
+			Debug("github rate limit")
+	}
+	return nil
 }
 
-func (p *GithubProvider) mustGetDefaultBranch() string {
-	var query defaultBranchQuery
-	err := p.client.Query(context.Background(), &query, p.defaultVariables())
-	if err != nil {
-		log.Fatal(err)
+func (p *GithubProvider) waitRateLimit(ctx context.Context) error {
+	l := p.rateLimit.RateLimit
+	if !p.rateLimit.known() || l.Remaining >= l.Cost {
+		return nil
+	}
+
+	wait := time.Until(l.ResetAt)
+	if wait > p.config.retryConfig().MaxWait {
+		return &RateLimitError{Reset: l.ResetAt, Err: fmt.Errorf("%d points remaining", l.Remaining)}
 	}
-	return query.Repository.DefaultBranchRef.Name
+	log.WithField("reset", l.ResetAt).Warn("Waiting for the github rate limit reset")
+	if err := sleep(ctx, wait); err != nil {
+		return err
+	}
+	p.rateLimit = rateLimitQuery{}
+	return nil
 }
 
-func (p *GithubProvider) tagFilter(v TagInfo) bool {
-	return p.GetVersionRegexp().MatchString(v.getId())
+func (p *GithubProvider) queryHistory(ctx context.Context, variables map[string]interface{}) (*historyQuery, error) {
+	var query historyQuery
+	if err := p.query(ctx, &query, variables); err != nil {
+		return nil, err
+	}
+	return &query, nil
 }
 
 func (p *GithubProvider) tagMapper(tag TagInfo, changeLog []model.ReleaseItem) model.Release {
@@ -256,84 +493,108 @@ func (p *GithubProvider) tagMapper(tag TagInfo, changeLog []model.ReleaseItem) m
 		CurrentVersion: tag.getId(),
 		Ref:            tag.getCommitId(),
 		Changelog:      changeLog,
-		VersionPattern: p.MustGetPattern(),
+		VersionPattern: p.pattern,
 	}
 }
 
-func (p *GithubProvider) mustQueryReleases() *tagsQuery {
-
+func (p *GithubProvider) queryReleases(ctx context.Context) (*tagsQuery, error) {
 	var query tagsQuery
-
-	variables := map[string]interface{}{
-		"owner": githubv4.String(p.Owner),
-		"name":  githubv4.String(p.Repo),
+	if err := p.query(ctx, &query, p.defaultVariables()); err != nil {
+		log.WithError(err).Debug("cannot get last tags")
+		return nil, err
 	}
-	err := p.client.Query(context.Background(), &query, variables)
+	return &query, nil
+}
+
+func (p *GithubProvider) queryConfigFile(ctx context.Context) (*configFileQuery, error) {
+	branch, err := p.getBranch(ctx)
 	if err != nil {
-		log.WithError(err).Fatal("cannot get last tags")
+		return nil, err
 	}
-	return &query
-}
 
-func (p *GithubProvider) mustQueryConfigFile() *configFileQuery {
 	var query configFileQuery
 	variables := p.defaultVariables()
-	variables["file"] = githubv4.String(p.mustGetBranch() + ":" + model.DefaultConfigFile)
-
-	err := p.client.Query(context.Background(), &query, variables)
-	if err != nil {
-		log.Fatal(err)
+	variables["file"] = githubv4.String(branch + ":" + model.DefaultConfigFile)
+	if err := p.query(ctx, &query, variables); err != nil {
+		return nil, err
 	}
-	return &query
+	return &query, nil
 }
 
-// mustGetBranch get the target branch by order from:
+// getBranch get the target branch by order from:
 // 1. local param
 // 2. repository default branch
-func (p *GithubProvider) mustGetBranch() string {
+func (p *GithubProvider) getBranch(ctx context.Context) (string, error) {
 	if p.Branch != "" {
-		return p.Branch
+		return p.Branch, nil
 	}
 
 	if p.config.Branch != "" {
 		p.Branch = p.config.Branch
-		return p.Branch
+		return p.Branch, nil
 	}
 
-	variables := p.defaultVariables()
 	var query defaultBranchQuery
-
-	err := p.client.Query(context.Background(), &query, variables)
-	if err != nil {
-		log.Fatal(err)
+	if err := p.query(ctx, &query, p.defaultVariables()); err != nil {
+		return "", err
 	}
 	p.Branch = query.Repository.DefaultBranchRef.Name
-	return p.Branch
+	return p.Branch, nil
 }
 
-func (p *GithubProvider) GetVersionRegexp() *regexp.Regexp {
+// versionRegexp matches the release tags, it is built once from the pattern
+func (p *GithubProvider) versionRegexp(ctx context.Context) (*regexp.Regexp, error) {
 	if p.VersionRegexp != nil {
-		return p.VersionRegexp
+		return p.VersionRegexp, nil
 	}
 
-	p.VersionRegexp = GetVersionRegexp(p.MustGetPattern())
-	return p.VersionRegexp
+	pattern, err := p.GetPattern(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.VersionRegexp = GetVersionRegexp(pattern)
+	return p.VersionRegexp, nil
 }
 
-func (p *GithubProvider) getReleaseBoundary(release string) (string, string, error) {
+// getReleaseBoundary returns the commit of the release and the commit of the previous release.
+// The previous release is the most recent tag that is an ancestor of the release
+func (p *GithubProvider) getReleaseBoundary(ctx context.Context, release string) (string, string, error) {
 	var first, last string
 
-	tags := p.mustQueryReleases()
+	tags, err := p.queryReleases(ctx)
+	if err != nil {
+		return "", "", err
+	}
 
 	TagNodes := tags.Repository.Refs.TagNodes
 	for i, t := range TagNodes {
 		if t.getId() == release {
 			first = t.getCommitId()
-			if i != 0 {
-				last = TagNodes[i-1].getCommitId()
+			for j := i - 1; j >= 0; j-- {
+				ok, err := p.isAncestor(ctx, TagNodes[j].Name, t.Name)
+				if err != nil {
+					return "", "", err
+				}
+				if ok {
+					last = TagNodes[j].getCommitId()
+					break
+				}
 			}
 		}
 	}
 
 	return first, last, nil
 }
+
+// isAncestor checks that the tag is reachable from the head ref
+func (p *GithubProvider) isAncestor(ctx context.Context, tag string, head string) (bool, error) {
+	var query compareQuery
+	variables := p.defaultVariables()
+	variables["base"] = githubv4.String("refs/tags/" + tag)
+	variables["head"] = githubv4.String(head)
+
+	if err := p.query(ctx, &query, variables); err != nil {
+		return false, err
+	}
+	return query.isAncestor(), nil
+}
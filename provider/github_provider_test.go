@@ -1,10 +1,14 @@
 package provider
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/shurcooL/githubv4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -51,6 +55,68 @@ func TestGithubProvider_NewGithubProvider_constructor(t *testing.T) {
 	assert.Equal(t, "repo", p.Repo)
 }
 
+func TestGithubProvider_NewGithubProvider_enterprise(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, mustReadFile("../fixtures/github/releases.response.json"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := NewGithubProvider("owner", "repo", "token", &GithubProviderConfig{ApiURL: server.URL + "/api/v3"})
+	require.NoError(t, err)
+
+	query, err := p.queryReleases(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, query.GetTags(), 2)
+}
+
+func TestGithubGraphqlURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://github.example.com", "https://github.example.com/api/graphql"},
+		{"https://github.example.com/", "https://github.example.com/api/graphql"},
+		{"https://github.example.com/api/v3", "https://github.example.com/api/graphql"},
+		{"https://github.example.com/api/graphql", "https://github.example.com/api/graphql"},
+		{"https://api.github.com", "https://api.github.com/graphql"},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			assert.Equal(t, test.expected, GithubGraphqlURL(test.url))
+		})
+	}
+}
+
+func TestGithubProvider_Links(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"", "https://github.com/owner/repo/commit/abc"},
+		{"https://api.github.com", "https://github.com/owner/repo/commit/abc"},
+		{"https://github.example.com/", "https://github.example.com/owner/repo/commit/abc"},
+		{"https://github.example.com/api/v3", "https://github.example.com/owner/repo/commit/abc"},
+		{"https://github.example.com/api/graphql", "https://github.example.com/owner/repo/commit/abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			p, err := NewGithubProvider("owner", "repo", "token", &GithubProviderConfig{ApiURL: test.url})
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, p.Links().CommitURL("abc"))
+		})
+	}
+}
+
+func TestGithubProvider_Links_avatar(t *testing.T) {
+	p, err := NewGithubProvider("owner", "repo", "token", &GithubProviderConfig{ApiURL: "https://github.example.com/api/v3"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.example.com/tauf.png", p.Links().AvatarURL("tauf"))
+}
+
 func TestGithubProvider_NewGithubProvider_obfuscateToken(t *testing.T) {
 	tests := []struct {
 		token    string
@@ -75,18 +141,24 @@ func TestConfigurationError_Error(t *testing.T) {
 	assert.Equal(t, "Invalid configuration", err.Error())
 }
 
-func TestGithubProvider_GetVersionRegexp_createMatcherOnce(t *testing.T) {
+func TestGithubProvider_versionRegexp_createMatcherOnce(t *testing.T) {
 	p1, _ := NewGithubProvider("owner", "repo", "token", &GithubProviderConfig{Pattern: "first"})
-	assert.Equal(t, true, p1.GetVersionRegexp().MatchString("first"))
+	re1, err := p1.versionRegexp(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, true, re1.MatchString("first"))
 	p1.config.Pattern = "second"
 
 	p2, _ := NewGithubProvider("owner", "repo", "token", &GithubProviderConfig{Pattern: "second"})
-	p2.VersionRegexp = p1.GetVersionRegexp()
-	assert.Equal(t, true, p2.GetVersionRegexp().MatchString("first"))
-	assert.Equal(t, false, p1.GetVersionRegexp().MatchString("second"))
+	p2.VersionRegexp = re1
+	re2, err := p2.versionRegexp(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, true, re2.MatchString("first"))
+	re1, err = p1.versionRegexp(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, false, re1.MatchString("second"))
 }
 
-func TestGithubProvider_GetVersionRegexp_semver(t *testing.T) {
+func TestGithubProvider_versionRegexp_semver(t *testing.T) {
 	tests := []struct {
 		pattern  string
 		match    string
@@ -109,12 +181,14 @@ func TestGithubProvider_GetVersionRegexp_semver(t *testing.T) {
 			p, _ := NewGithubProvider("owner", "repo", "token", &GithubProviderConfig{
 				Pattern: test.pattern,
 			})
-			assert.Equal(t, test.expected, p.GetVersionRegexp().MatchString(test.match))
+			re, err := p.versionRegexp(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, re.MatchString(test.match))
 		})
 	}
 }
 
-func TestGithubProvider_GetVersionRegexp_date(t *testing.T) {
+func TestGithubProvider_versionRegexp_date(t *testing.T) {
 	tests := []struct {
 		pattern  string
 		match    string
@@ -129,7 +203,9 @@ func TestGithubProvider_GetVersionRegexp_date(t *testing.T) {
 			p, _ := NewGithubProvider("owner", "repo", "token", &GithubProviderConfig{
 				Pattern: test.pattern,
 			})
-			assert.Equal(t, test.expected, p.GetVersionRegexp().MatchString(test.match))
+			re, err := p.versionRegexp(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, re.MatchString(test.match))
 		})
 	}
 }
@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tauffredou/nextver/model"
+)
+
+const gitlabPageSize = 100
+
+// GitlabProvider uses the GitLab REST API (v4) to fetch data
+type GitlabProvider struct {
+	*apiProvider
+	client  *restClient
+	Host    string
+	Project string
+}
+
+type GitlabProviderConfig struct {
+	Branch  string
+	Pattern string
+	// ApiURL overrides the API endpoint, https://<host>/api/v4 by default
+	ApiURL     string
+	HttpClient *http.Client
+}
+
+// NewGitlabProvider creates a provider for the project (group/subgroup/name) hosted on host.
+// The token is optional for public projects
+func NewGitlabProvider(host string, project string, token string, config *GitlabProviderConfig) (*GitlabProvider, error) {
+	if host == "" || project == "" || config == nil {
+		return nil, &ConfigurationError{}
+	}
+
+	apiURL := config.ApiURL
+	if apiURL == "" {
+		apiURL = "https://" + host + "/api/v4"
+	}
+	log.WithField("url", apiURL).WithField("token", obfuscateToken(token)).Debug("Init gitlab provider")
+
+	client := newRestClient(apiURL, config.HttpClient)
+	if token != "" {
+		client.header.Set("PRIVATE-TOKEN", token)
+	}
+
+	p := &GitlabProvider{
+		client:  client,
+		Host:    host,
+		Project: project,
+	}
+	p.apiProvider = newApiProvider(p, config.Pattern, config.Branch)
+	return p, nil
+}
+
+// CreateRelease creates the tag on ref and the associated GitLab release
+func (p *GitlabProvider) CreateRelease(ctx context.Context, name string, ref string, description string) error {
+	body := map[string]string{
+		"tag_name":    name,
+		"name":        name,
+		"ref":         ref,
+		"description": description,
+	}
+	_, err := p.client.post(ctx, p.projectPath("/releases"), body, nil)
+	return err
+}
+
+type gitlabCommit struct {
+	ID         string    `json:"id"`
+	Message    string    `json:"message"`
+	AuthorName string    `json:"author_name"`
+	AuthoredAt time.Time `json:"authored_date"`
+	ParentIDs  []string  `json:"parent_ids"`
+}
+
+func (p *GitlabProvider) projectName() string { return p.Project }
+
+func (p *GitlabProvider) Links() model.Links {
+	base := "https://" + p.Host + "/" + p.Project
+	return model.Links{Commit: base + "/-/commit/{ref}", Issue: base + "/-/issues/{number}"}
+}
+
+func (p *GitlabProvider) projectPath(path string) string {
+	return "/projects/" + url.PathEscape(p.Project) + path
+}
+
+func (p *GitlabProvider) listTags(ctx context.Context) ([]apiTag, error) {
+	tags := make([]apiTag, 0)
+	for page := "1"; page != ""; {
+		var resp []struct {
+			Name   string `json:"name"`
+			Commit struct {
+				ID string `json:"id"`
+			} `json:"commit"`
+		}
+		r, err := p.client.get(ctx, p.projectPath("/repository/tags"), gitlabPage(page), &resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range resp {
+			tags = append(tags, apiTag{Name: t.Name, Commit: t.Commit.ID})
+		}
+		page = r.Header.Get("X-Next-Page")
+	}
+	return tags, nil
+}
+
+func (p *GitlabProvider) defaultBranch(ctx context.Context) (string, error) {
+	var resp struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	_, err := p.client.get(ctx, p.projectPath(""), nil, &resp)
+	return resp.DefaultBranch, err
+}
+
+func (p *GitlabProvider) readFile(ctx context.Context, ref string, path string) ([]byte, error) {
+	var content []byte
+	_, err := p.client.get(ctx, p.projectPath("/repository/files/"+url.PathEscape(path)+"/raw"), url.Values{"ref": {ref}}, &content)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	return content, err
+}
+
+func (p *GitlabProvider) isAncestor(ctx context.Context, tag apiTag, head string) (bool, error) {
+	var resp struct {
+		ID string `json:"id"`
+	}
+	_, err := p.client.get(ctx, p.projectPath("/repository/merge_base"), url.Values{"refs[]": {tag.Commit, head}}, &resp)
+	if err != nil {
+		return false, err
+	}
+	return resp.ID == tag.Commit, nil
+}
+
+func (p *GitlabProvider) history(ctx context.Context, base string, head string) ([]model.ReleaseItem, error) {
+	commits := make([]gitlabCommit, 0)
+
+	if base == "" {
+		for page := "1"; page != ""; {
+			var resp []gitlabCommit
+			query := gitlabPage(page)
+			query.Set("ref_name", head)
+			r, err := p.client.get(ctx, p.projectPath("/repository/commits"), query, &resp)
+			if err != nil {
+				return nil, err
+			}
+			commits = append(commits, resp...)
+			page = r.Header.Get("X-Next-Page")
+		}
+	} else {
+		var resp struct {
+			Commits []gitlabCommit `json:"commits"`
+		}
+		_, err := p.client.get(ctx, p.projectPath("/repository/compare"), url.Values{"from": {base}, "to": {head}}, &resp)
+		if err != nil {
+			return nil, err
+		}
+		// compare lists the oldest commit first
+		for i := len(resp.Commits) - 1; i >= 0; i-- {
+			commits = append(commits, resp.Commits[i])
+		}
+	}
+
+	result := make([]model.ReleaseItem, 0)
+	for _, c := range commits {
+		/* filter merge commit */
+		if len(c.ParentIDs) < 2 {
+			result = append(result, model.NewReleaseItem(c.ID, c.AuthorName, c.AuthoredAt, c.Message))
+		}
+	}
+	return result, nil
+}
+
+func gitlabPage(page string) url.Values {
+	return url.Values{
+		"page":     {page},
+		"per_page": {strconv.Itoa(gitlabPageSize)},
+	}
+}
@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitlab serves a project with the fakeHistory
+func fakeGitlab(t *testing.T) *httptest.Server {
+	commits := func(ids []string) []interface{} {
+		res := make([]interface{}, 0)
+		for _, c := range ids {
+			res = append(res, map[string]interface{}{"id": c, "message": fakeMessages[c], "author_name": "tauf", "authored_date": fakeDate.Format(time.RFC3339), "parent_ids": []string{}})
+		}
+		return res
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/group/sub/project", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]string{"default_branch": "master"})
+	})
+	mux.HandleFunc("/api/v4/projects/group/sub/project/repository/tags", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("PRIVATE-TOKEN"))
+		if r.URL.Query().Get("page") == "1" {
+			w.Header().Set("X-Next-Page", "2")
+			writeJSON(t, w, []interface{}{
+				map[string]interface{}{"name": "v2.0.0", "commit": map[string]string{"id": "c5"}},
+				map[string]interface{}{"name": "not-a-release", "commit": map[string]string{"id": "c2"}},
+			})
+			return
+		}
+		writeJSON(t, w, []interface{}{
+			map[string]interface{}{"name": "v1.1.0", "commit": map[string]string{"id": "c3"}},
+			map[string]interface{}{"name": "v1.0.0", "commit": map[string]string{"id": "c1"}},
+		})
+	})
+	mux.HandleFunc("/api/v4/projects/group/sub/project/repository/files/.nextver/config.yml/raw", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "master", r.URL.Query().Get("ref"))
+		mustWrite(w, "pattern: vSEMVER\n")
+	})
+	mux.HandleFunc("/api/v4/projects/group/sub/project/repository/merge_base", func(w http.ResponseWriter, r *http.Request) {
+		refs := r.URL.Query()["refs[]"]
+		if !assert.Len(t, refs, 2) {
+			http.Error(w, "two refs expected", http.StatusBadRequest)
+			return
+		}
+		if fakeIsAncestor(refs[0], refs[1]) {
+			writeJSON(t, w, map[string]string{"id": refs[0]})
+			return
+		}
+		writeJSON(t, w, map[string]string{"id": "other"})
+	})
+	mux.HandleFunc("/api/v4/projects/group/sub/project/repository/compare", func(w http.ResponseWriter, r *http.Request) {
+		from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		writeJSON(t, w, map[string]interface{}{"commits": commits(oldestFirst(fakeCommitsBetween(from, to)))})
+	})
+	mux.HandleFunc("/api/v4/projects/group/sub/project/repository/commits", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, commits(fakeHistory[r.URL.Query().Get("ref_name")]))
+	})
+	mux.HandleFunc("/api/v4/projects/group/sub/project/releases", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		var body map[string]string
+		if !readJSON(t, w, r, &body) {
+			return
+		}
+		assert.Equal(t, "v1.2.0", body["tag_name"])
+		assert.Equal(t, "master", body["ref"])
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(t, w, body)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newFakeGitlabProvider(t *testing.T, server *httptest.Server) *GitlabProvider {
+	p, err := NewGitlabProvider("gitlab.example.com", "group/sub/project", "secret", &GitlabProviderConfig{
+		ApiURL: server.URL + "/api/v4",
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestGitlabProvider_Links(t *testing.T) {
+	p, err := NewGitlabProvider("gitlab.example.com", "group/sub/project", "", &GitlabProviderConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://gitlab.example.com/group/sub/project/-/commit/abc", p.Links().CommitURL("abc"))
+	assert.Equal(t, "https://gitlab.example.com/group/sub/project/-/issues/12", p.Links().IssueURL("12"))
+}
+
+func TestGitlabProvider_NewGitlabProvider_emptyConfig(t *testing.T) {
+	_, err := NewGitlabProvider("gitlab.com", "group/project", "token", nil)
+	assert.Equal(t, &ConfigurationError{}, err)
+}
+
+func TestGitlabProvider_NewGitlabProvider_emptyProject(t *testing.T) {
+	_, err := NewGitlabProvider("gitlab.com", "", "token", &GitlabProviderConfig{})
+	assert.Equal(t, &ConfigurationError{}, err)
+}
+
+func TestGitlabProvider_GetReleases(t *testing.T) {
+	server := fakeGitlab(t)
+	defer server.Close()
+
+	actual, err := newFakeGitlabProvider(t, server).GetReleases(context.Background())
+	require.NoError(t, err)
+	require.Len(t, actual, 3)
+	assert.Equal(t, "v2.0.0", actual[0].CurrentVersion)
+	assert.Equal(t, "c5", actual[0].Ref)
+	assert.Equal(t, "v1.1.0", actual[1].CurrentVersion)
+	assert.Equal(t, "v1.0.0", actual[2].CurrentVersion)
+	assert.Equal(t, "group/sub/project", actual[2].Project)
+}
+
+func TestGitlabProvider_GetRelease_next(t *testing.T) {
+	server := fakeGitlab(t)
+	defer server.Close()
+
+	actual, err := newFakeGitlabProvider(t, server).GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", actual.CurrentVersion)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "feature 4", actual.Changelog[0].Title)
+	assert.Equal(t, "with details", actual.Changelog[0].Detail)
+	assert.Equal(t, "v1.2.0", actual.MustNextVersion())
+}
+
+func TestGitlabProvider_GetRelease_named(t *testing.T) {
+	server := fakeGitlab(t)
+	defer server.Close()
+
+	actual, err := newFakeGitlabProvider(t, server).GetRelease(context.Background(), "v1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", actual.CurrentVersion)
+	require.Len(t, actual.Changelog, 2)
+	assert.Equal(t, "fix 3", actual.Changelog[0].Title)
+	assert.Equal(t, "feature 2", actual.Changelog[1].Title)
+}
+
+func TestGitlabProvider_GetRelease_oldest(t *testing.T) {
+	server := fakeGitlab(t)
+	defer server.Close()
+
+	actual, err := newFakeGitlabProvider(t, server).GetRelease(context.Background(), "v1.0.0")
+	require.NoError(t, err)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "Initial commit", actual.Changelog[0].Title)
+}
+
+func TestGitlabProvider_GetRelease_unknown(t *testing.T) {
+	server := fakeGitlab(t)
+	defer server.Close()
+
+	_, err := newFakeGitlabProvider(t, server).GetRelease(context.Background(), "v9.9.9")
+	assert.Error(t, err)
+}
+
+func TestGitlabProvider_VersionPattern_fromFile(t *testing.T) {
+	server := fakeGitlab(t)
+	defer server.Close()
+
+	assert.Equal(t, "vSEMVER", newFakeGitlabProvider(t, server).VersionPattern(context.Background()))
+}
+
+func TestGitlabProvider_CreateRelease(t *testing.T) {
+	server := fakeGitlab(t)
+	defer server.Close()
+
+	err := newFakeGitlabProvider(t, server).CreateRelease(context.Background(), "v1.2.0", "master", "changelog")
+	assert.NoError(t, err)
+}
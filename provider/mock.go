@@ -1,22 +1,119 @@
 package provider
 
-import "github.com/tauffredou/nextver/model"
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"time"
 
+	"github.com/tauffredou/nextver/model"
+)
+
+const mockBranch = "master"
+
+// MockProvider is an in-memory provider with a linear history, built with AddCommit, Tag and AddRelease
+//
+//	p := NewMockProvider().
+//		AddCommit("feat: first", "tauf", date).
+//		Tag("v1.0.0").
+//		AddCommit("fix: second", "tauf", date.Add(time.Hour))
 type MockProvider struct {
+	*apiProvider
+	commits []model.ReleaseItem
+	tags    []apiTag
 }
 
-func (p *MockProvider) GetLatestRelease() *model.Release {
-	panic("implement me")
+func NewMockProvider() *MockProvider {
+	p := &MockProvider{}
+	p.apiProvider = newApiProvider(p, "", mockBranch)
+	return p
 }
 
-func (p *MockProvider) AddRelease(r model.Release) {
+// Pattern sets the version pattern, model.DefaultPattern is used otherwise
+func (p *MockProvider) Pattern(pattern string) *MockProvider {
+	p.pattern = pattern
+	p.versionRegexp = nil
+	return p
+}
 
+// AddCommit adds a commit on top of the history
+func (p *MockProvider) AddCommit(message string, author string, date time.Time) *MockProvider {
+	h := sha1.Sum([]byte(fmt.Sprintf("%d %s %s %s", len(p.commits), author, date, message)))
+	p.commits = append(p.commits, model.NewReleaseItem(hex.EncodeToString(h[:]), author, date, message))
+	return p
 }
 
-func (p *MockProvider) GetRelease(name string) (*model.Release, error) {
-	return &model.Release{}, nil
+// Tag tags the last commit
+func (p *MockProvider) Tag(name string) *MockProvider {
+	p.tags = append(p.tags, apiTag{Name: name, Commit: p.head()})
+	return p
 }
 
-func NewMockProvider() *MockProvider {
-	return &MockProvider{}
+// AddRelease adds the changelog of the release on top of the history, then tags it
+func (p *MockProvider) AddRelease(r model.Release) *MockProvider {
+	for i := len(r.Changelog) - 1; i >= 0; i-- {
+		item := r.Changelog[i]
+		if item.ID == "" {
+			item.ID = fmt.Sprintf("%s-%d", r.CurrentVersion, i)
+		}
+		p.commits = append(p.commits, item)
+	}
+	return p.Tag(r.CurrentVersion)
+}
+
+// CreateRelease tags ref, either a commit or the branch
+func (p *MockProvider) CreateRelease(ctx context.Context, name string, ref string, description string) error {
+	i := p.indexOf(ref)
+	if i == -1 && len(p.commits) > 0 {
+		return &NotFoundError{Resource: "ref " + ref}
+	}
+	commit := ""
+	if i != -1 {
+		commit = p.commits[i].ID
+	}
+	p.tags = append(p.tags, apiTag{Name: name, Commit: commit})
+	return nil
+}
+
+func (p *MockProvider) head() string {
+	if len(p.commits) == 0 {
+		return ""
+	}
+	return p.commits[len(p.commits)-1].ID
+}
+
+// indexOf gets the position of the ref in the history, -1 when it is unknown or the history is empty
+func (p *MockProvider) indexOf(ref string) int {
+	if ref == mockBranch {
+		return len(p.commits) - 1
+	}
+	for i := range p.commits {
+		if p.commits[i].ID == ref {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *MockProvider) projectName() string { return "mock" }
+
+func (p *MockProvider) listTags(ctx context.Context) ([]apiTag, error) { return p.tags, nil }
+
+func (p *MockProvider) defaultBranch(ctx context.Context) (string, error) { return mockBranch, nil }
+
+func (p *MockProvider) readFile(ctx context.Context, ref string, path string) ([]byte, error) {
+	return nil, nil
+}
+
+func (p *MockProvider) isAncestor(ctx context.Context, tag apiTag, head string) (bool, error) {
+	return p.indexOf(tag.Commit) <= p.indexOf(head), nil
+}
+
+func (p *MockProvider) history(ctx context.Context, base string, head string) ([]model.ReleaseItem, error) {
+	result := make([]model.ReleaseItem, 0)
+	for i := p.indexOf(head); i > p.indexOf(base); i-- {
+		result = append(result, p.commits[i])
+	}
+	return result, nil
 }
@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+)
+
+func newTestMockProvider() *MockProvider {
+	return NewMockProvider().
+		AddCommit("feat: first", "tauf", testDate).
+		Tag("v1.0.0").
+		AddCommit("fix: second", "tauf", testDate.Add(time.Hour)).
+		Tag("v1.0.1").
+		AddCommit("feat(api): third", "tauf", testDate.Add(2*time.Hour))
+}
+
+func TestMockProvider_implementsProvider(t *testing.T) {
+	var _ Provider = NewMockProvider()
+	var _ ReleaseCreator = NewMockProvider()
+}
+
+func TestMockProvider_GetReleases(t *testing.T) {
+	actual, err := newTestMockProvider().GetReleases(context.Background())
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	assert.Equal(t, "v1.0.1", actual[0].CurrentVersion)
+	assert.Equal(t, "v1.0.0", actual[1].CurrentVersion)
+	assert.Equal(t, model.DefaultPattern, actual[0].VersionPattern)
+}
+
+func TestMockProvider_GetRelease_next(t *testing.T) {
+	actual, err := newTestMockProvider().GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.1", actual.CurrentVersion)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "third", actual.Changelog[0].Title)
+	assert.Equal(t, "v1.1.0", actual.MustNextVersion())
+}
+
+func TestMockProvider_GetRelease_named(t *testing.T) {
+	p := newTestMockProvider()
+
+	actual, err := p.GetRelease(context.Background(), "v1.0.1")
+	require.NoError(t, err)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "second", actual.Changelog[0].Title)
+
+	actual, err = p.GetRelease(context.Background(), "v1.0.0")
+	require.NoError(t, err)
+	require.Len(t, actual.Changelog, 1)
+	assert.Equal(t, "first", actual.Changelog[0].Title)
+
+	_, err = p.GetRelease(context.Background(), "v9.0.0")
+	assert.Error(t, err)
+}
+
+func TestMockProvider_AddRelease(t *testing.T) {
+	p := NewMockProvider().
+		Pattern("SEMVER").
+		AddRelease(model.Release{
+			CurrentVersion: "1.0.0",
+			Changelog: []model.ReleaseItem{
+				model.NewReleaseItem("", "tauf", testDate.Add(time.Hour), "fix: second"),
+				model.NewReleaseItem("", "tauf", testDate, "feat: first"),
+			},
+		}).
+		AddCommit("feat: next", "tauf", testDate.Add(2*time.Hour))
+
+	actual, err := p.GetRelease(context.Background(), "1.0.0")
+	require.NoError(t, err)
+	require.Len(t, actual.Changelog, 2)
+	assert.Equal(t, "second", actual.Changelog[0].Title)
+	assert.Equal(t, "first", actual.Changelog[1].Title)
+
+	next, err := p.GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", next.MustNextVersion())
+}
+
+func TestMockProvider_CreateRelease(t *testing.T) {
+	p := newTestMockProvider()
+	require.NoError(t, p.CreateRelease(context.Background(), "v1.1.0", "master", ""))
+	assert.Error(t, p.CreateRelease(context.Background(), "v1.2.0", "unknown", ""))
+
+	actual, err := p.GetRelease(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", actual.CurrentVersion)
+	assert.Empty(t, actual.Changelog)
+}
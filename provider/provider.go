@@ -1,14 +1,33 @@
 package provider
 
 import (
+	"context"
 	"github.com/tauffredou/nextver/model"
 	"regexp"
 	"strings"
 )
 
+// Provider reads the releases of a repository.
+// The context cancels the queries and the history walks
 type Provider interface {
-	GetReleases() ([]model.Release, error)
-	GetRelease(name string) (*model.Release, error)
+	GetReleases(ctx context.Context) ([]model.Release, error)
+	GetRelease(ctx context.Context, name string) (*model.Release, error)
+}
+
+// ReleaseCreator is implemented by providers able to publish a release
+type ReleaseCreator interface {
+	CreateRelease(ctx context.Context, name string, ref string, description string) error
+}
+
+// Linker is implemented by providers knowing the web pages of the repository
+type Linker interface {
+	Links() model.Links
+}
+
+// ConfigReader is implemented by providers reading the configuration file of the repository
+type ConfigReader interface {
+	// Config returns the configuration file, nil or empty without file
+	Config(ctx context.Context) (*model.Config, error)
 }
 
 func GetVersionRegexp(pattern string) *regexp.Regexp {
@@ -1,55 +1,206 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
-	"regexp"
+	"strings"
+)
+
+const (
+	GithubProviderName    = "github"
+	GitlabProviderName    = "gitlab"
+	GiteaProviderName     = "gitea"
+	BitbucketProviderName = "bitbucket"
+	GitProviderName       = "git"
 )
 
 type ProviderFactory struct {
-	TokenReader func() string
-	Pattern     string
+	TokenReader          func() string
+	GitlabTokenReader    func() string
+	GiteaTokenReader     func() string
+	BitbucketTokenReader func() string
+	Pattern              string
+	// Branch is the target branch, the default branch of the repository, or HEAD for a git repository, when empty
+	Branch string
+	// Provider forces the kind of provider instead of guessing it from the repository
+	Provider string
+	// ApiURL overrides the API endpoint of the provider
+	ApiURL string
+	// GithubURL is the url of a Github Enterprise Server
+	GithubURL string
+	// GithubHosts are the custom host names served by the github provider
+	GithubHosts []string
+	// Retry configures the retries of the github queries, DefaultRetryConfig is used when nil
+	Retry *RetryConfig
+	// Cache stores the API responses, they are only memoized for the life of each provider when nil
+	Cache *Cache
+	// PullRequests builds the github changelogs from the merged pull requests instead of the commits
+	PullRequests bool
 }
 
-func (f *ProviderFactory) CreateProvider(repo string) (Provider, error) {
-	r, err := ParseRepo(repo)
+// CreateProvider creates the provider serving the repository.
+// The provider is either forced by name or the first registered provider matching the repository
+func (f *ProviderFactory) CreateProvider(ctx context.Context, repo string) (Provider, error) {
+	r, err := f.findRegistration(repo)
 	if err != nil {
 		return nil, err
 	}
-	switch v := r.(type) {
-	case GithubRepository:
-		provider, err := NewGithubProvider(v.Owner, v.Repo, f.TokenReader(), &GithubProviderConfig{Pattern: f.Pattern})
-		if err != nil {
-			return nil, err
+	// the changelog would silently be built from the commits
+	if f.PullRequests && r.Name != GithubProviderName {
+		return nil, &ConfigurationError{Reason: "the pull requests are only read by the github provider, not by " + r.Name}
+	}
+	return r.New(ctx, repo, f)
+}
+
+func (f *ProviderFactory) findRegistration(repo string) (ProviderRegistration, error) {
+	if f.Provider != "" {
+		r, ok := lookupProvider(f.Provider)
+		if !ok {
+			return ProviderRegistration{}, fmt.Errorf("unknown provider %s", f.Provider)
+		}
+		return r, nil
+	}
+
+	for _, r := range registeredProviders() {
+		if r.Match != nil && r.Match(repo, f) {
+			return r, nil
 		}
-		return provider, nil
-	case GitRepository:
-		return NewGitProvider(v.path, f.Pattern), nil
-	default:
-		return nil, fmt.Errorf("unhandled repo type %+v", v)
 	}
+	return ProviderRegistration{}, &InvalidRepositoryError{repo: repo}
+}
 
+// ParseRepo reads the repository using the factory configuration: the forced provider and the Github hosts.
+// Only the built-in providers have a typed repository
+func (f *ProviderFactory) ParseRepo(repo string) (interface{}, error) {
+	r, err := f.findRegistration(repo)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRepoAs(r.Name, repo)
+}
+
+func (f *ProviderFactory) githubHosts() []string {
+	hosts := append([]string{}, f.GithubHosts...)
+	if f.GithubURL != "" {
+		if u, err := url.Parse(f.GithubURL); err == nil && u.Host != "" && u.Host != "api.github.com" {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	return hosts
 }
 
+// githubURL gets the endpoint of the repository: the configured one or the default endpoint of the host
+func (f *ProviderFactory) githubURL(r GithubRepository) string {
+	switch {
+	case f.GithubURL != "":
+		return f.GithubURL
+	case f.Provider == GithubProviderName && f.ApiURL != "":
+		return f.ApiURL
+	case r.Host != "":
+		return "https://" + r.Host
+	}
+	return ""
+}
+
+// httpClient is the client of the REST providers, nil lets them use their default client
+func (f *ProviderFactory) httpClient() *http.Client {
+	if f.Cache == nil {
+		return nil
+	}
+	return &http.Client{Transport: f.Cache.Transport(nil)}
+}
+
+func readToken(reader func() string) string {
+	if reader == nil {
+		return ""
+	}
+	return reader()
+}
+
+// ParseRepo guesses the built-in provider of the repository and reads it
 func ParseRepo(repo string) (interface{}, error) {
-	if repo == "" {
-		return nil, &InvalidRepositoryError{repo: repo}
+	f := &ProviderFactory{}
+	for _, r := range builtinProviders {
+		if r.Match(repo, f) {
+			return ParseRepoAs(r.Name, repo)
+		}
 	}
+	return nil, &InvalidRepositoryError{repo: repo}
+}
 
-	re := regexp.MustCompile(`^(https://|git@)?github.com[:/]([a-zA-Z0-9-]+)/([a-zA-Z0-9-]+)(\.git)?$`)
-	if re.MatchString(repo) {
-		v := re.FindStringSubmatch(repo)
-		return GithubRepository{Owner: v[2], Repo: v[3]}, nil
+// ParseRepoAs reads the repository for the given kind of provider
+func ParseRepoAs(provider string, repo string) (interface{}, error) {
+	if provider == GitProviderName {
+		if _, err := os.Stat(repo); err == nil {
+			return GitRepository{path: repo}, nil
+		}
+		if isRemoteURL(repo) {
+			return RemoteGitRepository{URL: repo}, nil
+		}
+		return nil, &InvalidRepositoryError{repo: repo}
 	}
 
-	if _, err := os.Stat(repo); os.IsNotExist(err) {
+	u, ok := parseRemoteURL(repo)
+	if !ok {
 		return nil, &InvalidRepositoryError{repo: repo}
 	}
+	owner, name := u.ownerAndName()
 
-	return GitRepository{path: repo}, nil
+	switch provider {
+	case GithubProviderName:
+		if owner == "" {
+			return nil, &InvalidRepositoryError{repo: repo}
+		}
+		r := GithubRepository{Owner: owner, Repo: name}
+		if !strings.EqualFold(u.Host, "github.com") {
+			r.Host = u.Host
+		}
+		return r, nil
+	case GitlabProviderName:
+		return GitlabRepository{Host: u.Host, Project: u.Path}, nil
+	case GiteaProviderName:
+		if owner == "" {
+			return nil, &InvalidRepositoryError{repo: repo}
+		}
+		return GiteaRepository{Host: u.Host, Owner: owner, Repo: name}, nil
+	case BitbucketProviderName:
+		// Bitbucket Data Center http clone urls are prefixed by scm
+		u.Path = strings.TrimPrefix(u.Path, "scm/")
+		owner, name = u.ownerAndName()
+		if owner == "" {
+			return nil, &InvalidRepositoryError{repo: repo}
+		}
+		return BitbucketRepository{Host: u.Host, Owner: owner, Repo: name}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %s", provider)
+	}
 }
 
+// GithubRepository is hosted on github.com when Host is empty, on a Github Enterprise Server otherwise
 type GithubRepository struct {
+	Host  string
+	Owner string
+	Repo  string
+}
+
+type GitlabRepository struct {
+	Host    string
+	Project string
+}
+
+type GiteaRepository struct {
+	Host  string
+	Owner string
+	Repo  string
+}
+
+// BitbucketRepository is hosted on Bitbucket Cloud when Host is bitbucket.org, on Bitbucket Data Center otherwise.
+// Owner is either the workspace (Cloud) or the project key (Data Center)
+type BitbucketRepository struct {
+	Host  string
 	Owner string
 	Repo  string
 }
@@ -58,6 +209,72 @@ type GitRepository struct {
 	path string
 }
 
+// RemoteGitRepository is a plain git remote, without API
+type RemoteGitRepository struct {
+	URL string
+}
+
+type remoteURL struct {
+	Host string
+	Path string
+}
+
+// ownerAndName splits a owner/name path, owner is empty when the path has more segments
+func (u remoteURL) ownerAndName() (string, string) {
+	v := strings.Split(u.Path, "/")
+	if len(v) != 2 {
+		return "", ""
+	}
+	return v[0], v[1]
+}
+
+// parseRemoteURL reads the host and the repository path from the usual remote forms:
+// host/path, https://host/path, ssh://git@host/path and git@host:path
+func parseRemoteURL(repo string) (remoteURL, bool) {
+	var host, path string
+	switch {
+	case strings.Contains(repo, "://"):
+		u, err := url.Parse(repo)
+		if err != nil {
+			return remoteURL{}, false
+		}
+		host = u.Host
+		if u.Scheme != "http" && u.Scheme != "https" {
+			host = u.Hostname()
+		}
+		path = u.Path
+	case strings.HasPrefix(repo, "git@"):
+		i := strings.Index(repo, ":")
+		if i == -1 {
+			return remoteURL{}, false
+		}
+		host = repo[len("git@"):i]
+		path = repo[i+1:]
+	default:
+		i := strings.Index(repo, "/")
+		if i == -1 {
+			return remoteURL{}, false
+		}
+		host = repo[:i]
+		path = repo[i:]
+		// a host name has at least a dot: anything else is a local path
+		if !strings.Contains(host, ".") || strings.HasPrefix(host, ".") {
+			return remoteURL{}, false
+		}
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") {
+		return remoteURL{}, false
+	}
+	return remoteURL{Host: host, Path: path}, true
+}
+
+// isRemoteURL tells if the repository is a git url rather than a local path
+func isRemoteURL(repo string) bool {
+	return strings.Contains(repo, "://") || strings.HasPrefix(repo, "git@")
+}
+
 type InvalidRepositoryError struct{ repo string }
 
 func (e InvalidRepositoryError) Error() string { return fmt.Sprintf("Invalid repository %s", e.repo) }
@@ -1,7 +1,9 @@
 package provider
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -11,7 +13,7 @@ func TestCreateProvider_github(t *testing.T) {
 		Pattern:     "vSEMVER",
 	}
 
-	p, err := f.CreateProvider("github.com/test/test-rep")
+	p, err := f.CreateProvider(context.Background(), "github.com/test/test-rep")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, p)
@@ -21,18 +23,143 @@ func TestCreateProvider_github(t *testing.T) {
 	assert.Equal(t, "test-rep", gp.Repo)
 }
 
+func TestCreateProvider_githubNoToken(t *testing.T) {
+	f := ProviderFactory{Pattern: "vSEMVER"}
+
+	_, err := f.CreateProvider(context.Background(), "github.com/test/test-rep")
+
+	assert.IsType(t, &ConfigurationError{}, err)
+}
+
+func TestCreateProvider_gitlab(t *testing.T) {
+	f := ProviderFactory{
+		Pattern:  "vSEMVER",
+		Provider: GitlabProviderName,
+	}
+
+	p, err := f.CreateProvider(context.Background(), "https://gitlab.example.com/group/project.git")
+
+	assert.NoError(t, err)
+	assert.IsType(t, &GitlabProvider{}, p)
+	gp := p.(*GitlabProvider)
+	assert.Equal(t, "gitlab.example.com", gp.Host)
+	assert.Equal(t, "group/project", gp.Project)
+}
+
+func TestCreateProvider_explicitProvider(t *testing.T) {
+	f := ProviderFactory{
+		Pattern:  "vSEMVER",
+		Provider: GiteaProviderName,
+		ApiURL:   "https://git.example.com/api/v1",
+	}
+
+	p, err := f.CreateProvider(context.Background(), "https://git.example.com/owner/repo.git")
+
+	assert.NoError(t, err)
+	assert.IsType(t, &GiteaProvider{}, p)
+	gp := p.(*GiteaProvider)
+	assert.Equal(t, "owner", gp.Owner)
+	assert.Equal(t, "repo", gp.Repo)
+	assert.Equal(t, "https://git.example.com/api/v1", gp.client.baseURL)
+}
+
+func TestCreateProvider_bitbucket(t *testing.T) {
+	f := ProviderFactory{Pattern: "vSEMVER"}
+
+	p, err := f.CreateProvider(context.Background(), "bitbucket.org/owner/repo")
+	assert.NoError(t, err)
+	assert.IsType(t, &BitbucketCloudProvider{}, p)
+
+	// a Data Center host is selected explicitly
+	f.Provider = BitbucketProviderName
+	p, err = f.CreateProvider(context.Background(), "ssh://git@bitbucket.example.com:7999/prj/repo.git")
+	assert.NoError(t, err)
+	assert.IsType(t, &BitbucketServerProvider{}, p)
+	assert.Equal(t, "https://bitbucket.example.com/rest/api/1.0", p.(*BitbucketServerProvider).client.baseURL)
+}
+
+func TestParseRepoAs(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		repo     string
+		want     interface{}
+		wantErr  bool
+	}{
+		{name: "gitea", provider: GiteaProviderName, repo: "git.example.com/owner/repo", want: GiteaRepository{Host: "git.example.com", Owner: "owner", Repo: "repo"}},
+		{name: "gitea subgroup", provider: GiteaProviderName, repo: "git.example.com/group/owner/repo", wantErr: true},
+		{name: "gitlab", provider: GitlabProviderName, repo: "ssh://git@git.example.com/group/sub/repo.git", want: GitlabRepository{Host: "git.example.com", Project: "group/sub/repo"}},
+		{name: "github", provider: GithubProviderName, repo: "git@github.com:test/test-rep.git", want: GithubRepository{Owner: "test", Repo: "test-rep"}},
+		{name: "bitbucket", provider: BitbucketProviderName, repo: "ssh://git@git.example.com/project/repo.git", want: BitbucketRepository{Host: "git.example.com", Owner: "project", Repo: "repo"}},
+		{name: "gitlab self-hosted", provider: GitlabProviderName, repo: "https://gitlab.example.com:8443/group/project", want: GitlabRepository{Host: "gitlab.example.com:8443", Project: "group/project"}},
+		{name: "gitlab ssh", provider: GitlabProviderName, repo: "ssh://git@gitlab.example.com:2222/group/project.git", want: GitlabRepository{Host: "gitlab.example.com", Project: "group/project"}},
+		{name: "forgejo", provider: GiteaProviderName, repo: "git@forgejo.example.com:owner/repo.git", want: GiteaRepository{Host: "forgejo.example.com", Owner: "owner", Repo: "repo"}},
+		{name: "bitbucket server https", provider: BitbucketProviderName, repo: "https://bitbucket.example.com/scm/prj/repo.git", want: BitbucketRepository{Host: "bitbucket.example.com", Owner: "prj", Repo: "repo"}},
+		{name: "git", provider: GitProviderName, repo: ".", want: GitRepository{path: "."}},
+		{name: "unknown", provider: "svn", repo: "svn.example.com/owner/repo", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ParseRepoAs(test.provider, test.repo)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.want, actual)
+			}
+		})
+	}
+}
+
+func TestCreateProvider_githubEnterprise(t *testing.T) {
+	f := ProviderFactory{
+		TokenReader: func() string { return "aToken" },
+		Pattern:     "vSEMVER",
+		GithubHosts: []string{"code.example.com"},
+	}
+
+	r, err := f.ParseRepo("git@code.example.com:test/test-rep.git")
+	assert.NoError(t, err)
+	assert.Equal(t, GithubRepository{Host: "code.example.com", Owner: "test", Repo: "test-rep"}, r)
+	assert.Equal(t, "https://code.example.com", f.githubURL(r.(GithubRepository)))
+
+	p, err := f.CreateProvider(context.Background(), "https://code.example.com/test/test-rep")
+	assert.NoError(t, err)
+	assert.IsType(t, &GithubProvider{}, p)
+}
+
+func TestCreateProvider_githubURL(t *testing.T) {
+	f := ProviderFactory{
+		TokenReader: func() string { return "aToken" },
+		GithubURL:   "https://ghes.example.com/api/v3",
+	}
+
+	r, err := f.ParseRepo("ghes.example.com/test/test-rep")
+	assert.NoError(t, err)
+	assert.Equal(t, GithubRepository{Host: "ghes.example.com", Owner: "test", Repo: "test-rep"}, r)
+	assert.Equal(t, "https://ghes.example.com/api/v3", f.githubURL(r.(GithubRepository)))
+	assert.Empty(t, f.GithubHosts)
+}
+
 func TestCreateProvider_git(t *testing.T) {
 	f := ProviderFactory{
 		Pattern: "vSEMVER",
 	}
 
-	p, err := f.CreateProvider(".")
+	p, err := f.CreateProvider(context.Background(), ".")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, p)
 	assert.IsType(t, &GitProvider{}, p)
 	gp := p.(*GitProvider)
 	assert.Equal(t, ".", gp.path)
+	assert.Equal(t, "", gp.branch)
+
+	f.Branch = "release/1.x"
+	p, err = f.CreateProvider(context.Background(), ".")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "release/1.x", p.(*GitProvider).branch)
+	}
 }
 
 func TestRepoParam(t *testing.T) {
@@ -52,6 +179,22 @@ func TestRepoParam(t *testing.T) {
 		{name: "github https", repo: "https://github.com/test/test-rep", want: GithubRepository{Owner: "test", Repo: "test-rep"}},
 		{name: "github https .git", repo: "https://github.com/test/test-rep.git", want: GithubRepository{Owner: "test", Repo: "test-rep"}},
 		{name: "github git", repo: "git@github.com:test/test-rep.git", want: GithubRepository{Owner: "test", Repo: "test-rep"}},
+
+		{name: "gitlab short", repo: "gitlab.com/group/project", want: GitlabRepository{Host: "gitlab.com", Project: "group/project"}},
+		{name: "gitlab https subgroup", repo: "https://gitlab.com/group/sub/project.git", want: GitlabRepository{Host: "gitlab.com", Project: "group/sub/project"}},
+		{name: "gitlab git", repo: "git@gitlab.com:group/project.git", want: GitlabRepository{Host: "gitlab.com", Project: "group/project"}},
+		{name: "gitea", repo: "gitea.com/owner/repo", want: GiteaRepository{Host: "gitea.com", Owner: "owner", Repo: "repo"}},
+		{name: "codeberg", repo: "codeberg.org/owner/repo", want: GiteaRepository{Host: "codeberg.org", Owner: "owner", Repo: "repo"}},
+		{name: "bitbucket cloud", repo: "bitbucket.org/owner/repo", want: BitbucketRepository{Host: "bitbucket.org", Owner: "owner", Repo: "repo"}},
+		{name: "bitbucket cloud git", repo: "git@bitbucket.org:owner/repo.git", want: BitbucketRepository{Host: "bitbucket.org", Owner: "owner", Repo: "repo"}},
+		{name: "remote git https", repo: "https://git.example.com/x.git", want: RemoteGitRepository{URL: "https://git.example.com/x.git"}},
+		{name: "remote git ssh", repo: "ssh://git@git.example.com/group/x.git", want: RemoteGitRepository{URL: "ssh://git@git.example.com/group/x.git"}},
+
+		// the self-hosted instances are not guessed from their host name, see TestParseRepoAs
+		{name: "gitlab self-hosted", repo: "https://gitlab.example.com/group/project", want: RemoteGitRepository{URL: "https://gitlab.example.com/group/project"}},
+		{name: "gitlab lookalike", repo: "git@gitlab-mirror.corp:group/project.git", want: RemoteGitRepository{URL: "git@gitlab-mirror.corp:group/project.git"}},
+		{name: "github lookalike", repo: "https://notgithub.example.com/owner/repo.git", want: RemoteGitRepository{URL: "https://notgithub.example.com/owner/repo.git"}},
+		{name: "bitbucket server", repo: "ssh://git@bitbucket.example.com:7999/prj/repo.git", want: RemoteGitRepository{URL: "ssh://git@bitbucket.example.com:7999/prj/repo.git"}},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -64,3 +207,52 @@ func TestRepoParam(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateProvider_branch(t *testing.T) {
+	f := ProviderFactory{TokenReader: func() string { return "aToken" }, Pattern: "vSEMVER", Branch: "release/1.x"}
+	repos := map[string]func(Provider) string{
+		"github.com/owner/repo":          func(p Provider) string { return p.(*GithubProvider).config.Branch },
+		"gitlab.com/group/project":       func(p Provider) string { return p.(*GitlabProvider).branch },
+		"codeberg.org/owner/repo":        func(p Provider) string { return p.(*GiteaProvider).branch },
+		"bitbucket.org/owner/repo":       func(p Provider) string { return p.(*BitbucketCloudProvider).branch },
+		"bitbucket.example.com/prj/repo": func(p Provider) string { return p.(*BitbucketServerProvider).branch },
+	}
+	for repo, branch := range repos {
+		t.Run(repo, func(t *testing.T) {
+			if strings.HasPrefix(repo, "bitbucket.example.com") {
+				f.Provider = BitbucketProviderName
+			} else {
+				f.Provider = ""
+			}
+			p, err := f.CreateProvider(context.Background(), repo)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "release/1.x", branch(p))
+			}
+		})
+	}
+}
+
+func TestCreateProvider_pullRequestsOutsideGithub(t *testing.T) {
+	f := ProviderFactory{Pattern: "vSEMVER", PullRequests: true}
+	for _, repo := range []string{"gitlab.com/group/project", "."} {
+		_, err := f.CreateProvider(context.Background(), repo)
+		assert.True(t, IsConfigurationError(err), repo)
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	match := matchHost("gitlab.com")
+	tests := map[string]bool{
+		"gitlab.com/group/project":                  true,
+		"https://GitLab.com/group/project.git":      true,
+		"ssh://git@gitlab.com:22/group/project.git": true,
+		"git@eu.gitlab.com:group/project.git":       true,
+		"gitlab.example.com/group/project":          false,
+		"git@gitlab-mirror.corp:group/project.git":  false,
+		"https://notgitlab.com/group/project":       false,
+		".":                                         false,
+	}
+	for repo, expected := range tests {
+		assert.Equal(t, expected, match(repo, &ProviderFactory{}), repo)
+	}
+}
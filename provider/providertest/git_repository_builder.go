@@ -0,0 +1,143 @@
+// Package providertest helps testing the providers
+package providertest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// GitRepositoryBuilder creates a git repository on disk, to test the GitProvider without fixtures.
+// Any git error fails the test
+//
+//	b := providertest.NewGitRepositoryBuilder(t)
+//	defer b.Remove()
+//	b.AddCommit("feat: first", "tauf", date).Tag("v1.0.0")
+//	p := provider.NewGitProvider(b.Path, "vSEMVER")
+type GitRepositoryBuilder struct {
+	// Path is the directory of the repository
+	Path string
+	t    testing.TB
+	repo *git.Repository
+	wt   *git.Worktree
+	// files counts the files created by the commits
+	files int
+}
+
+// NewGitRepositoryBuilder initializes an empty repository in a temporary directory, on the master branch
+func NewGitRepositoryBuilder(t testing.TB) *GitRepositoryBuilder {
+	path, err := ioutil.TempDir("", "nextver-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &GitRepositoryBuilder{Path: path, t: t}
+
+	b.repo, err = git.PlainInit(path, false)
+	b.check(err)
+	b.wt, err = b.repo.Worktree()
+	b.check(err)
+	return b
+}
+
+// Remove deletes the repository
+func (b *GitRepositoryBuilder) Remove() {
+	b.check(os.RemoveAll(b.Path))
+}
+
+// Repository gives access to the underlying repository
+func (b *GitRepositoryBuilder) Repository() *git.Repository {
+	return b.repo
+}
+
+// AddCommit commits a new file on the current branch
+func (b *GitRepositoryBuilder) AddCommit(message string, author string, date time.Time) *GitRepositoryBuilder {
+	b.commit(message, author, date, nil)
+	return b
+}
+
+// Merge creates a merge commit of the branch into the current branch.
+// The tree of the current branch is kept: only the history matters to nextver
+func (b *GitRepositoryBuilder) Merge(branch string, message string, author string, date time.Time) *GitRepositoryBuilder {
+	ref, err := b.repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	b.check(err)
+	b.commit(message, author, date, []plumbing.Hash{b.Head(), ref.Hash()})
+	return b
+}
+
+// Tag creates a lightweight tag on HEAD
+func (b *GitRepositoryBuilder) Tag(name string) *GitRepositoryBuilder {
+	_, err := b.repo.CreateTag(name, b.Head(), nil)
+	b.check(err)
+	return b
+}
+
+// AnnotatedTag creates an annotated tag on HEAD
+func (b *GitRepositoryBuilder) AnnotatedTag(name string, message string) *GitRepositoryBuilder {
+	_, err := b.repo.CreateTag(name, b.Head(), &git.CreateTagOptions{
+		Tagger:  signature("tagger", time.Now()),
+		Message: message,
+	})
+	b.check(err)
+	return b
+}
+
+// Branch creates a branch on HEAD and checks it out
+func (b *GitRepositoryBuilder) Branch(name string) *GitRepositoryBuilder {
+	b.check(b.wt.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(name),
+		Create: true,
+	}))
+	return b
+}
+
+// Checkout switches to an existing branch
+func (b *GitRepositoryBuilder) Checkout(name string) *GitRepositoryBuilder {
+	b.check(b.wt.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(name),
+	}))
+	return b
+}
+
+// Head gets the commit of HEAD
+func (b *GitRepositoryBuilder) Head() plumbing.Hash {
+	ref, err := b.repo.Head()
+	b.check(err)
+	return ref.Hash()
+}
+
+func (b *GitRepositoryBuilder) commit(message string, author string, date time.Time, parents []plumbing.Hash) {
+	b.files++
+	name := fmt.Sprintf("file-%d", b.files)
+	f, err := b.wt.Filesystem.Create(name)
+	b.check(err)
+	_, err = f.Write([]byte(message))
+	b.check(err)
+	b.check(f.Close())
+	_, err = b.wt.Add(name)
+	b.check(err)
+
+	_, err = b.wt.Commit(message, &git.CommitOptions{
+		Author:  signature(author, date),
+		Parents: parents,
+	})
+	b.check(err)
+}
+
+func (b *GitRepositoryBuilder) check(err error) {
+	if err != nil {
+		b.t.Helper()
+		b.t.Fatal(err)
+	}
+}
+
+func signature(name string, date time.Time) *object.Signature {
+	email := strings.ToLower(strings.Replace(name, " ", ".", -1)) + "@example.com"
+	return &object.Signature{Name: name, Email: email, When: date}
+}
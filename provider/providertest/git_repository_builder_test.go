@@ -0,0 +1,51 @@
+package providertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+var testDate = time.Date(2019, 6, 25, 12, 35, 41, 0, time.UTC)
+
+func TestGitRepositoryBuilder(t *testing.T) {
+	b := NewGitRepositoryBuilder(t)
+	defer b.Remove()
+
+	b.AddCommit("feat: first", "tauf", testDate).
+		Tag("v1.0.0").
+		Branch("feature").
+		AddCommit("feat: feature", "tauf", testDate.Add(time.Hour))
+	feature := b.Head()
+	b.Checkout("master").
+		AddCommit("fix: bug", "Thomas Auffredou", testDate.Add(2*time.Hour)).
+		AnnotatedTag("v1.0.1", "release v1.0.1").
+		Merge("feature", "Merge branch 'feature'", "tauf", testDate.Add(3*time.Hour))
+
+	repo := b.Repository()
+	merge, err := repo.CommitObject(b.Head())
+	require.NoError(t, err)
+	assert.Equal(t, "Merge branch 'feature'", merge.Message)
+	require.Len(t, merge.ParentHashes, 2)
+	assert.Equal(t, feature, merge.ParentHashes[1])
+
+	fix, err := merge.Parent(0)
+	require.NoError(t, err)
+	assert.Equal(t, "thomas.auffredou@example.com", fix.Author.Email)
+	assert.True(t, testDate.Add(2*time.Hour).Equal(fix.Author.When))
+
+	tag, err := repo.Reference(plumbing.NewTagReferenceName("v1.0.0"), true)
+	require.NoError(t, err)
+	first, err := repo.CommitObject(tag.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, "feat: first", first.Message)
+
+	annotated, err := repo.Tag("v1.0.1")
+	require.NoError(t, err)
+	tagObject, err := repo.TagObject(annotated.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, fix.Hash, tagObject.Target)
+}
@@ -0,0 +1,220 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ProviderRegistration declares a provider to the ProviderFactory
+type ProviderRegistration struct {
+	// Name selects the provider explicitly (see ProviderFactory.Provider)
+	Name string
+	// Match tells if the provider serves the repository when no provider is forced
+	Match func(repo string, f *ProviderFactory) bool
+	// New creates the provider for the repository, the context bounds the initialization (e.g. a clone)
+	New func(ctx context.Context, repo string, f *ProviderFactory) (Provider, error)
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      []ProviderRegistration
+
+	githubRegexp = regexp.MustCompile(`^(https://|git@)?github\.com[:/]([a-zA-Z0-9-]+)/([a-zA-Z0-9-]+)(\.git)?$`)
+
+	// builtinProviders are consulted in order, git is the fallback
+	builtinProviders = []ProviderRegistration{
+		{Name: GithubProviderName, Match: matchGithub, New: newGithubProvider},
+		{Name: GitlabProviderName, Match: matchHost("gitlab.com"), New: newGitlabProvider},
+		{Name: GiteaProviderName, Match: matchHost("gitea.com", "codeberg.org"), New: newGiteaProvider},
+		{Name: BitbucketProviderName, Match: matchHost("bitbucket.org"), New: newBitbucketProvider},
+		{Name: GitProviderName, Match: func(string, *ProviderFactory) bool { return true }, New: newGitProvider},
+	}
+)
+
+// RegisterProvider adds a provider to the registry.
+// Registered providers are consulted before the built-in ones, in registration order
+func RegisterProvider(r ProviderRegistration) error {
+	if r.Name == "" || r.New == nil {
+		return fmt.Errorf("invalid provider registration %+v", r)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	for _, v := range registry {
+		if v.Name == r.Name {
+			return fmt.Errorf("provider %s already registered", r.Name)
+		}
+	}
+	registry = append(registry, r)
+	return nil
+}
+
+// ProviderNames lists the registered providers, then the built-in ones
+func ProviderNames() []string {
+	res := make([]string, 0)
+	for _, r := range registeredProviders() {
+		res = append(res, r.Name)
+	}
+	return res
+}
+
+func registeredProviders() []ProviderRegistration {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	res := make([]ProviderRegistration, 0, len(registry)+len(builtinProviders))
+	res = append(res, registry...)
+	return append(res, builtinProviders...)
+}
+
+func lookupProvider(name string) (ProviderRegistration, bool) {
+	for _, r := range registeredProviders() {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return ProviderRegistration{}, false
+}
+
+// matchHost matches the repositories of one of the hosts or of their subdomains.
+// The self-hosted instances are selected with --provider
+func matchHost(hosts ...string) func(string, *ProviderFactory) bool {
+	return func(repo string, _ *ProviderFactory) bool {
+		u, ok := parseRemoteURL(repo)
+		if !ok {
+			return false
+		}
+		host := strings.ToLower(u.Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		for _, h := range hosts {
+			if host == h || strings.HasSuffix(host, "."+h) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func matchGithub(repo string, f *ProviderFactory) bool {
+	if githubRegexp.MatchString(repo) {
+		return true
+	}
+
+	u, ok := parseRemoteURL(repo)
+	if !ok {
+		return false
+	}
+	for _, h := range f.githubHosts() {
+		if strings.EqualFold(u.Host, h) {
+			return true
+		}
+	}
+	return false
+}
+
+func newGithubProvider(ctx context.Context, repo string, f *ProviderFactory) (Provider, error) {
+	r, err := ParseRepoAs(GithubProviderName, repo)
+	if err != nil {
+		return nil, err
+	}
+	v := r.(GithubRepository)
+	p, err := NewGithubProvider(v.Owner, v.Repo, readToken(f.TokenReader), &GithubProviderConfig{
+		Pattern:      f.Pattern,
+		Branch:       f.Branch,
+		ApiURL:       f.githubURL(v),
+		Retry:        f.Retry,
+		Cache:        f.Cache,
+		PullRequests: f.PullRequests,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func newGitlabProvider(ctx context.Context, repo string, f *ProviderFactory) (Provider, error) {
+	r, err := ParseRepoAs(GitlabProviderName, repo)
+	if err != nil {
+		return nil, err
+	}
+	v := r.(GitlabRepository)
+	p, err := NewGitlabProvider(v.Host, v.Project, readToken(f.GitlabTokenReader), &GitlabProviderConfig{
+		Pattern:    f.Pattern,
+		Branch:     f.Branch,
+		ApiURL:     f.ApiURL,
+		HttpClient: f.httpClient(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func newGiteaProvider(ctx context.Context, repo string, f *ProviderFactory) (Provider, error) {
+	r, err := ParseRepoAs(GiteaProviderName, repo)
+	if err != nil {
+		return nil, err
+	}
+	v := r.(GiteaRepository)
+	p, err := NewGiteaProvider(v.Host, v.Owner, v.Repo, readToken(f.GiteaTokenReader), &GiteaProviderConfig{
+		Pattern:    f.Pattern,
+		Branch:     f.Branch,
+		ApiURL:     f.ApiURL,
+		HttpClient: f.httpClient(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func newBitbucketProvider(ctx context.Context, repo string, f *ProviderFactory) (Provider, error) {
+	r, err := ParseRepoAs(BitbucketProviderName, repo)
+	if err != nil {
+		return nil, err
+	}
+	v := r.(BitbucketRepository)
+	config := &BitbucketProviderConfig{
+		Pattern:    f.Pattern,
+		Branch:     f.Branch,
+		ApiURL:     f.ApiURL,
+		HttpClient: f.httpClient(),
+	}
+	token := readToken(f.BitbucketTokenReader)
+
+	if v.Host == BitbucketCloudHost {
+		p, err := NewBitbucketCloudProvider(v.Owner, v.Repo, token, config)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	p, err := NewBitbucketServerProvider(v.Host, v.Owner, v.Repo, token, config)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func newGitProvider(ctx context.Context, repo string, f *ProviderFactory) (Provider, error) {
+	r, err := ParseRepoAs(GitProviderName, repo)
+	if err != nil {
+		return nil, err
+	}
+	switch v := r.(type) {
+	case RemoteGitRepository:
+		p, err := NewRemoteGitProvider(ctx, v.URL, f.Pattern, f.Branch)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	default:
+		return NewGitProvider(r.(GitRepository).path, f.Pattern).Branch(f.Branch), nil
+	}
+}
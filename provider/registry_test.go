@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauffredou/nextver/model"
+)
+
+// saveRegistry returns a function restoring the registered providers
+func saveRegistry() func() {
+	saved := append([]ProviderRegistration{}, registry...)
+	return func() {
+		registryMutex.Lock()
+		registry = saved
+		registryMutex.Unlock()
+	}
+}
+
+// registeredProvider is a third-party provider remembering its repository
+type registeredProvider struct{ repo string }
+
+func (p *registeredProvider) GetReleases(ctx context.Context) ([]model.Release, error) {
+	return nil, nil
+}
+
+func (p *registeredProvider) GetRelease(ctx context.Context, name string) (*model.Release, error) {
+	return &model.Release{}, nil
+}
+
+func fakeRegistration(name string, host string) ProviderRegistration {
+	return ProviderRegistration{
+		Name:  name,
+		Match: matchHost(host),
+		New: func(ctx context.Context, repo string, f *ProviderFactory) (Provider, error) {
+			return &registeredProvider{repo: repo}, nil
+		},
+	}
+}
+
+func TestRegisterProvider(t *testing.T) {
+	defer saveRegistry()()
+
+	require.NoError(t, RegisterProvider(fakeRegistration("sourcehut", "sr.ht")))
+
+	f := &ProviderFactory{}
+	actual, err := f.CreateProvider(context.Background(), "git.sr.ht/~owner/repo")
+	require.NoError(t, err)
+	assert.Equal(t, &registeredProvider{repo: "git.sr.ht/~owner/repo"}, actual)
+	assert.Equal(t, "sourcehut", ProviderNames()[0])
+	assert.Contains(t, ProviderNames(), GitProviderName)
+}
+
+func TestRegisterProvider_forced(t *testing.T) {
+	defer saveRegistry()()
+
+	require.NoError(t, RegisterProvider(fakeRegistration("sourcehut", "sr.ht")))
+
+	f := &ProviderFactory{Provider: "sourcehut"}
+	actual, err := f.CreateProvider(context.Background(), "https://git.example.com/owner/repo")
+	require.NoError(t, err)
+	assert.IsType(t, &registeredProvider{}, actual)
+}
+
+func TestRegisterProvider_beforeBuiltins(t *testing.T) {
+	defer saveRegistry()()
+
+	require.NoError(t, RegisterProvider(fakeRegistration("mirror", "github.com")))
+
+	f := &ProviderFactory{}
+	actual, err := f.CreateProvider(context.Background(), "github.com/owner/repo")
+	require.NoError(t, err)
+	assert.IsType(t, &registeredProvider{}, actual)
+}
+
+func TestRegisterProvider_invalid(t *testing.T) {
+	defer saveRegistry()()
+
+	assert.Error(t, RegisterProvider(ProviderRegistration{Name: "noop"}))
+	require.NoError(t, RegisterProvider(fakeRegistration("sourcehut", "sr.ht")))
+	err := RegisterProvider(fakeRegistration("sourcehut", "sr.ht"))
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "already registered"))
+}
+
+func TestProviderFactory_unknownProvider(t *testing.T) {
+	f := &ProviderFactory{Provider: "unknown"}
+	_, err := f.CreateProvider(context.Background(), "github.com/owner/repo")
+	assert.EqualError(t, err, "unknown provider unknown")
+}
@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// restClient is a minimal JSON client shared by the REST API providers
+type restClient struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header
+}
+
+func newRestClient(baseURL string, httpClient *http.Client) *restClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: NewCache("", 0).Transport(nil)}
+	}
+	return &restClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+		header:     http.Header{},
+	}
+}
+
+// APIError is returned when a REST API answers with an error status
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, e.Message)
+}
+
+// get decodes the JSON response in v. When v is a *[]byte the raw body is returned
+func (c *restClient) get(ctx context.Context, path string, query url.Values, v interface{}) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, path, query, nil, v)
+}
+
+func (c *restClient) post(ctx context.Context, path string, body interface{}, v interface{}) (*http.Response, error) {
+	return c.do(ctx, http.MethodPost, path, nil, body, v)
+}
+
+func (c *restClient) do(ctx context.Context, method string, path string, query url.Values, body interface{}, v interface{}) (*http.Response, error) {
+	u := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		u = c.baseURL + path
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k := range c.header {
+		req.Header.Set(k, c.header.Get(k))
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp, classifyAPIError(&APIError{
+			Method:     method,
+			URL:        u,
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(msg)),
+		}, resp.Header)
+	}
+
+	switch out := v.(type) {
+	case nil:
+	case *[]byte:
+		*out, err = ioutil.ReadAll(resp.Body)
+	default:
+		err = json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp, err
+}
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RetryConfig tells how the transient failures of an API are retried
+type RetryConfig struct {
+	// MaxRetries is the number of retries of a failed request, 0 disables the retries
+	MaxRetries int
+	// MinBackoff is the wait before the first retry of a server error, it doubles at each retry up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxWait is the longest wait accepted for a rate limit, the RateLimitError is returned beyond
+	MaxWait time.Duration
+}
+
+// DefaultRetryConfig is used when the provider configuration has no retry configuration
+var DefaultRetryConfig = RetryConfig{
+	MaxRetries: 3,
+	MinBackoff: time.Second,
+	MaxBackoff: 30 * time.Second,
+	MaxWait:    time.Minute,
+}
+
+// backoff gets the wait before the retry following the attempt (0 for the first request)
+func (c RetryConfig) backoff(attempt int) time.Duration {
+	d := c.MinBackoff
+	for i := 0; i < attempt && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.MaxBackoff {
+		return c.MaxBackoff
+	}
+	return d
+}
+
+// retryTransport retries the requests failing with a network error, a server error (5xx) or a rate limit.
+// Rate limits are waited for using the Retry-After or the reset headers
+type retryTransport struct {
+	base   http.RoundTripper
+	config RetryConfig
+}
+
+func newRetryTransport(base http.RoundTripper, config RetryConfig) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base, config: config}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		r := req.WithContext(req.Context())
+		if body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if req.Context().Err() != nil {
+			return resp, err
+		}
+		wait, ok := t.retryWait(resp, err, attempt)
+		if !ok || attempt >= t.config.MaxRetries {
+			return giveUp(req, resp, err)
+		}
+
+		fields := log.Fields{"url": req.URL.String(), "attempt": attempt + 1, "wait": wait}
+		if err != nil {
+			log.WithFields(fields).WithError(err).Warn("Retrying request")
+		} else {
+			log.WithFields(fields).WithField("status", resp.StatusCode).Warn("Retrying request")
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryWait tells if the request can be retried and how long to wait before
+func (t *retryTransport) retryWait(resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		return t.config.backoff(attempt), true
+	}
+
+	var wait time.Duration
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		wait = t.config.backoff(attempt)
+		if d, ok := retryAfter(resp.Header); ok {
+			wait = d
+		}
+	case isRateLimited(resp):
+		wait = t.config.backoff(attempt)
+		if reset := rateLimitReset(resp.Header); !reset.IsZero() {
+			wait = time.Until(reset)
+		}
+	default:
+		return 0, false
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, wait <= t.config.MaxWait
+}
+
+// giveUp returns the last response, a rate limited response becomes a RateLimitError:
+// the clients reading the status only would see a permission error
+func giveUp(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+	if err != nil || !isRateLimited(resp) {
+		return resp, err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return nil, &RateLimitError{
+		Reset: rateLimitReset(resp.Header),
+		Err:   fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status),
+	}
+}
+
+// isRateLimited tells if the response is a rate limit rather than a permission error
+func isRateLimited(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
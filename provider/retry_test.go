@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRetryConfig = RetryConfig{
+	MaxRetries: 2,
+	MinBackoff: time.Millisecond,
+	MaxBackoff: 5 * time.Millisecond,
+	MaxWait:    time.Second,
+}
+
+// fakeFlakyGithub answers with the failures first, then with the releases
+func fakeFlakyGithub(t *testing.T, calls *int32, failures ...func(w http.ResponseWriter)) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, req *http.Request) {
+		i := int(atomic.AddInt32(calls, 1)) - 1
+		if i < len(failures) {
+			failures[i](w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, mustReadFile("../fixtures/github/releases.response.json"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func newFlakyGithubProvider(t *testing.T, server *httptest.Server) *GithubProvider {
+	config := testRetryConfig
+	p, err := NewGithubProvider("owner", "repo", "token", &GithubProviderConfig{
+		ApiURL:  server.URL + "/api/v3",
+		Pattern: "vSEMVER",
+		Retry:   &config,
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func status(code int, header map[string]string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for k, v := range header {
+			w.Header().Set(k, v)
+		}
+		http.Error(w, http.StatusText(code), code)
+	}
+}
+
+func TestRetry_serverError(t *testing.T) {
+	var calls int32
+	server := fakeFlakyGithub(t, &calls, status(http.StatusBadGateway, nil), status(http.StatusBadGateway, nil))
+	defer server.Close()
+
+	actual, err := newFlakyGithubProvider(t, server).GetReleases(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, actual, 2)
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestRetry_serverError_exhausted(t *testing.T) {
+	var calls int32
+	server := fakeFlakyGithub(t, &calls,
+		status(http.StatusBadGateway, nil),
+		status(http.StatusBadGateway, nil),
+		status(http.StatusBadGateway, nil))
+	defer server.Close()
+
+	_, err := newFlakyGithubProvider(t, server).GetReleases(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestRetry_secondaryRateLimit(t *testing.T) {
+	var calls int32
+	server := fakeFlakyGithub(t, &calls, status(http.StatusForbidden, map[string]string{"Retry-After": "0"}))
+	defer server.Close()
+
+	_, err := newFlakyGithubProvider(t, server).GetReleases(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestRetry_rateLimitReset(t *testing.T) {
+	var calls int32
+	reset := strconv.FormatInt(time.Now().Unix(), 10)
+	server := fakeFlakyGithub(t, &calls, status(http.StatusForbidden, map[string]string{
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     reset,
+	}))
+	defer server.Close()
+
+	_, err := newFlakyGithubProvider(t, server).GetReleases(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestRetry_rateLimitTooLong(t *testing.T) {
+	var calls int32
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	server := fakeFlakyGithub(t, &calls, status(http.StatusForbidden, map[string]string{
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     reset,
+	}))
+	defer server.Close()
+
+	_, err := newFlakyGithubProvider(t, server).GetReleases(context.Background())
+	assert.True(t, IsRateLimitError(err), "%v", err)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestRetry_forbidden(t *testing.T) {
+	var calls int32
+	server := fakeFlakyGithub(t, &calls, status(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "4000"}))
+	defer server.Close()
+
+	_, err := newFlakyGithubProvider(t, server).GetReleases(context.Background())
+	assert.True(t, IsAuthenticationError(err), "%v", err)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestGithubProvider_waitRateLimit(t *testing.T) {
+	p := &GithubProvider{config: &GithubProviderConfig{Retry: &testRetryConfig}}
+	p.rateLimit.RateLimit.Cost = 1
+	p.rateLimit.RateLimit.Remaining = 0
+	p.rateLimit.RateLimit.ResetAt = time.Now().Add(time.Hour)
+
+	err := p.waitRateLimit(context.Background())
+	assert.True(t, IsRateLimitError(err), "%v", err)
+
+	p.rateLimit.RateLimit.ResetAt = time.Now().Add(time.Millisecond)
+	assert.NoError(t, p.waitRateLimit(context.Background()))
+}
+
+func TestRetryConfig_backoff(t *testing.T) {
+	c := RetryConfig{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, c.backoff(0))
+	assert.Equal(t, 2*time.Second, c.backoff(1))
+	assert.Equal(t, 4*time.Second, c.backoff(2))
+	assert.Equal(t, 5*time.Second, c.backoff(3))
+}